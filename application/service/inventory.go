@@ -50,6 +50,8 @@ func (s *InventoryService) CheckAvailability(ctx context.Context, items []*entit
 }
 
 // ReserveStock 在庫を予約（引当）
+//
+//logic:tag 在庫
 func (s *InventoryService) ReserveStock(ctx context.Context, items []*entity.CartItem) error {
 	reservedItems := make([]*entity.CartItem, 0, len(items))
 
@@ -69,6 +71,8 @@ func (s *InventoryService) ReserveStock(ctx context.Context, items []*entity.Car
 }
 
 // ReleaseStock 予約済み在庫を解放
+//
+//logic:tag 在庫
 func (s *InventoryService) ReleaseStock(ctx context.Context, items []*entity.CartItem) error {
 	var lastErr error
 	for _, item := range items {
@@ -82,6 +86,8 @@ func (s *InventoryService) ReleaseStock(ctx context.Context, items []*entity.Car
 }
 
 // CommitStock 在庫を確定（実際に減らす）
+//
+//logic:tag 在庫
 func (s *InventoryService) CommitStock(ctx context.Context, items []*entity.CartItem) error {
 	for _, item := range items {
 		err := s.inventoryRepo.Commit(ctx, item.ProductID, item.Quantity)
@@ -93,6 +99,8 @@ func (s *InventoryService) CommitStock(ctx context.Context, items []*entity.Cart
 }
 
 // RestoreStock 在庫を復元（返金時）
+//
+//logic:tag 在庫 返金
 func (s *InventoryService) RestoreStock(ctx context.Context, items []*entity.CartItem) error {
 	for _, item := range items {
 		// 在庫を戻す（Releaseとは異なり、実在庫を増やす）
@@ -29,6 +29,8 @@ func NewPaymentService(customerRepo repository.ICustomerRepository) *PaymentServ
 }
 
 // ProcessPayment 決済を処理
+//
+//logic:tag 決済
 func (s *PaymentService) ProcessPayment(ctx context.Context, pricing *entity.Pricing, method entity.PaymentMethod, pointsToUse int, customer *entity.Customer) (*entity.Payment, error) {
 	// 決済方法のバリデーション
 	if err := s.ValidatePaymentMethod(method, customer, pointsToUse); err != nil {
@@ -121,6 +123,8 @@ func (s *PaymentService) ValidatePaymentMethod(method entity.PaymentMethod, cust
 }
 
 // RefundPayment 返金処理
+//
+//logic:tag 決済 返金
 func (s *PaymentService) RefundPayment(ctx context.Context, payment *entity.Payment, customer *entity.Customer) error {
 	if !payment.CanRefund() {
 		return &entity.RefundError{
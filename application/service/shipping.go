@@ -89,6 +89,8 @@ func (s *ShippingService) UpdateShippingStatus(ctx context.Context, shipping *en
 }
 
 // generateTrackingNumber 追跡番号を生成（モック）
+//
+//logic:ignore
 func (s *ShippingService) generateTrackingNumber(method entity.ShippingMethod) string {
 	prefix := "STD"
 	switch method {
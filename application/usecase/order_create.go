@@ -1,4 +1,4 @@
-//go:generate sh -c "cd ../../ && go run internal/logic/*.go"
+//go:generate sh -c "cd ../../ && go run ./internal/logic"
 package usecase
 
 import (
@@ -58,6 +58,9 @@ func NewOrderCreateUseCase(
 }
 
 // CreateOrder 注文を作成する
+//
+//logic:entrypoint
+//logic:tag 注文 在庫 決済
 func (uc *OrderCreateUseCase) CreateOrder(ctx context.Context, req validator.CreateOrderRequest) (*entity.Order, error) {
 	// 1. リクエストのバリデーション
 	if err := uc.orderValidator.ValidateCreateOrder(req); err != nil {
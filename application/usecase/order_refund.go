@@ -1,4 +1,4 @@
-//go:generate sh -c "cd ../../ && go run internal/logic/*.go"
+//go:generate sh -c "cd ../../ && go run ./internal/logic"
 package usecase
 
 import (
@@ -46,6 +46,9 @@ func NewOrderRefundUseCase(
 }
 
 // RefundOrder 注文を返金する
+//
+//logic:entrypoint
+//logic:tag 返金 決済
 func (uc *OrderRefundUseCase) RefundOrder(ctx context.Context, req validator.RefundRequest) (*entity.Order, error) {
 	// 1. リクエストのバリデーション
 	if err := uc.orderValidator.ValidateRefund(req); err != nil {
@@ -1,4 +1,4 @@
-//go:generate sh -c "cd ../../ && go run internal/logic/*.go"
+//go:generate sh -c "cd ../../ && go run ./internal/logic"
 package usecase
 
 import (
@@ -56,6 +56,9 @@ func NewOrderStatusUseCase(
 }
 
 // GetOrderStatus 注文ステータスを取得する
+//
+//logic:entrypoint
+//logic:tag 注文
 func (uc *OrderStatusUseCase) GetOrderStatus(ctx context.Context, orderID string) (*OrderStatusResponse, error) {
 	// 1. 注文を取得
 	order, err := uc.orderRepo.GetByID(ctx, orderID)
@@ -70,6 +73,9 @@ func (uc *OrderStatusUseCase) GetOrderStatus(ctx context.Context, orderID string
 }
 
 // GetCustomerOrders 顧客の注文一覧を取得する
+//
+//logic:entrypoint
+//logic:tag 注文
 func (uc *OrderStatusUseCase) GetCustomerOrders(ctx context.Context, customerID string) ([]*OrderStatusResponse, error) {
 	// 1. 顧客の存在確認
 	_, err := uc.customerRepo.GetByID(ctx, customerID)
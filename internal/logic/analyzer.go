@@ -6,29 +6,117 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"unicode"
+
+	"github.com/shibuya-mizuho/logic-mermaid-pages/internal/logictrace"
 )
 
 type Analyzer struct {
 	config            *Config
 	fileSet           *token.FileSet
 	files             map[string]*ast.File
+	sources           map[string][]byte // ファイル名 -> ソースコード
 	functions         map[string]*FunctionInfo
-	functionCallNodes map[string]string // ノードID -> 関数呼び出し名のマッピング
+	functionCallNodes map[string]string    // ノードID -> 関数呼び出し名のマッピング
+	nodeInfos         map[string]*NodeInfo // ノードID -> ノード情報のマッピング
+	stmtNodes         map[ast.Node]string  // 文 -> ノードIDのマッピング
+	currentFunc       *ast.FuncDecl        // Mermaid生成中の関数
+	errorBranch       string               // 解析中のエラー分岐の条件ノードID（分岐外では空）
+	currentGroup      string               // //logic:group で指定された解析中のグループ名
+
+	// 型情報
+	modules           []goModule // 解析するワークスペース内のモジュール（入れ子のモジュールが先）
+	importer          types.Importer
+	info              *types.Info
+	packages          map[string]*types.Package             // インポートパス -> 型チェック済みパッケージ
+	funcDecls         map[*types.Func]*ast.FuncDecl         // モジュール内の全関数宣言
+	functionsByDecl   map[*ast.FuncDecl]*FunctionInfo       // 関数宣言 -> ドキュメント化対象の関数
+	namedTypeCache    []*types.Named                        // モジュール内の名前付き型
+	selectedDecls     map[*ast.FuncDecl]functionDirectives  // ドキュメント化する関数宣言 -> ディレクティブ
+	filteredDecls     map[*ast.FuncDecl]bool                // 対象のファイルにあるが、Functions の条件で除外した関数宣言
+	returnedErrors    map[*types.Func][]ReturnedError       // 関数 -> 返しうるエラー（メモ化）
+	errorStack        map[*types.Func]int                   // 返しうるエラーを探索中の関数 -> 探索の深さ
+	errorCycleStart   int                                   // 探索中に循環した呼び出し先のうち最も浅い深さ（循環がなければ -1）
+	packageFiles      map[string][]*ast.File                // インポートパス -> 構文木
+	constCommentCache map[string]map[token.Pos]constComment // インポートパス -> 定数のコメント（メモ化）
+	traceCalls        map[logictrace.Position]bool          // logictrace の呼び出しがある位置（表示しない文）
+
+	diagrams      []*Diagram        // 関数以外の観点で生成した図
+	traces        []*ExecutionTrace // フローチャート上で再生する実行経路
+	checkFailures []string          // check モードで失敗とする検出結果
 }
 
 type FunctionInfo struct {
-	PackageName     string
-	FileName        string
-	FunctionName    string
-	FullName        string
-	ReceiverType    string
-	MermaidCode     string
-	CalledFunctions []string
-	Comments        string
-	SourceCode      string
+	ID                   string // インポートパスで修飾した関数の識別子（a.functions のキー）
+	Module               string // 関数が属するモジュールのパス（型情報がなければ空）
+	PackagePath          string // パッケージのインポートパス（モジュール外ならディレクトリ）
+	PackageName          string
+	FileName             string
+	FunctionName         string
+	FullName             string
+	ReceiverType         string
+	ReceiverTypeParams   []string    // ジェネリック型のレシーバーの型パラメータ名（*Cache[K, V] なら K, V）
+	TypeParams           []TypeParam // 関数の型パラメータと型制約
+	MermaidCode          string
+	HappyPathMermaidCode string // エラー分岐を畳んだMermaidコード
+	CommentMermaidCode   string // コメントのみ表示のMermaidコード
+	CommentHappyPathCode string // コメントのみ表示でエラー分岐を畳んだMermaidコード
+	CalledFunctions      []string
+	Identifiers          []string // 関数で使っている識別子（全文検索用）
+	Comments             string
+	SourceCode           string
+	StartLine            int
+	EndLine              int
+	Nodes                map[string]*NodeInfo
+	CallTargets          map[string][]string // 呼び出し名 -> 解決した呼び出し先の関数（ID）
+	ReturnedErrors       []ReturnedError
+	Compensations        []CompensationExit
+	ExpandedMermaidCode  string    // 呼び出し先をサブグラフとして展開したMermaidコード
+	UsedTypes            []TypeRef // 使用しているモジュール内の構造体・インターフェース
+	EntryPoint           bool      // //logic:entrypoint が付いた入口の関数
+	Tags                 []string  // //logic:tag で付けたタグ
+	Metrics              FunctionMetrics
+	Coverage             *float64          // テストのカバレッジ率（%）。coverprofile がなければ nil
+	References           []SymbolReference // 参照しているモジュール内の型・フィールド・定数
+
+	flowchart *flowchart
+}
+
+// flowchart は1関数分のフローチャートの生成結果
+type flowchart struct {
+	nodes         []string
+	edges         []string
+	callNodes     map[string]string // ノードID -> 関数呼び出し名
+	mermaidCode   string
+	happyPathCode string
+	commentCode   string
+	commentHappy  string
+	compensations []CompensationExit
+}
+
+// NodeInfo フローチャートのノードに対応するソースの行範囲とエラー経路の分類
+type NodeInfo struct {
+	StartLine   int
+	EndLine     int
+	ErrorCheck  bool   // err != nil などのエラー判定
+	ErrorPath   bool   // エラー処理の経路上にある
+	ErrorReturn bool   // nilでないエラーを返すreturn
+	ErrorBranch string // 所属するエラー分岐の条件ノードID
+
+	MissingCompensation bool // 失敗時の出口で補償処理が漏れている
+
+	Comment string // コメントのみ表示で使う文言（//logic:label または直前のコメント）
+	Group   string // //logic:group で指定されたグループ名
+
+	Coverage string // テストで実行されたかどうか（covered / uncovered、不明なら空）
+
+	Defines []string // 書き込む変数・フィールド（pricing.CouponDiscount など）
+	Uses    []string // 読み取る変数・フィールド
 }
 
 func NewAnalyzer(config *Config) *Analyzer {
@@ -36,8 +124,28 @@ func NewAnalyzer(config *Config) *Analyzer {
 		config:            config,
 		fileSet:           token.NewFileSet(),
 		files:             make(map[string]*ast.File),
+		sources:           make(map[string][]byte),
 		functions:         make(map[string]*FunctionInfo),
 		functionCallNodes: make(map[string]string),
+		nodeInfos:         make(map[string]*NodeInfo),
+		stmtNodes:         make(map[ast.Node]string),
+		info: &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		},
+		packages:          make(map[string]*types.Package),
+		funcDecls:         make(map[*types.Func]*ast.FuncDecl),
+		functionsByDecl:   make(map[*ast.FuncDecl]*FunctionInfo),
+		selectedDecls:     make(map[*ast.FuncDecl]functionDirectives),
+		filteredDecls:     make(map[*ast.FuncDecl]bool),
+		returnedErrors:    make(map[*types.Func][]ReturnedError),
+		errorStack:        make(map[*types.Func]int),
+		errorCycleStart:   -1,
+		packageFiles:      make(map[string][]*ast.File),
+		constCommentCache: make(map[string]map[token.Pos]constComment),
+		traceCalls:        make(map[logictrace.Position]bool),
 	}
 }
 
@@ -60,17 +168,74 @@ func (a *Analyzer) AnalyzeAllTargetFiles() (map[string]*FunctionInfo, error) {
 		}
 	}
 
-	// 全関数を解析
-	for fileName, file := range a.files {
-		a.analyzeFunctionsInFile(fileName, file)
+	// 型チェック
+	a.typeCheck()
+
+	// 全関数を解析（同じ名前の関数の ID がファイル名で決まるよう、ファイル名の順に解析する）
+	fileNames := make([]string, 0, len(a.files))
+	for fileName := range a.files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	// 呼び出し先が除外した関数かどうかをフローチャートの生成時に判定できるよう、先に対象の関数を決める
+	for _, fileName := range fileNames {
+		a.selectFunctionsInFile(a.files[fileName])
+	}
+	for _, fileName := range fileNames {
+		a.analyzeFunctionsInFile(fileName, a.files[fileName])
 	}
 
 	// 呼び出しグラフを構築
 	a.buildCallGraph()
 
+	// 返しうるエラーを解析
+	a.analyzeReturnedErrors()
+
+	// 呼び出し先を展開したフローチャートを生成
+	for _, funcInfo := range a.functions {
+		funcInfo.ExpandedMermaidCode = a.generateExpandedMermaidCode(funcInfo)
+	}
+
+	// ステータスの状態遷移図を生成
+	a.analyzeStateMachines()
+
+	// 構造体・インターフェースのクラス図を生成
+	a.analyzeClassDiagrams()
+
+	// パッケージ依存関係図を生成し、レイヤールールを検査
+	a.analyzeLayers()
+
+	// コンストラクタによる依存性注入の配線図を生成
+	a.analyzeWiring()
+
+	// 定数と条件式中の数値リテラルの一覧を生成
+	a.analyzeBusinessRules()
+
+	// 型・フィールド・定数の参照を記録（逆引き用）
+	a.analyzeReferences()
+
+	// ノードごとに書き込み・読み取りする変数とフィールドを記録
+	a.analyzeDataFlow()
+
+	// 関数ごとの複雑度とリスクの指標を計算
+	a.analyzeMetrics()
+
+	// テストのカバレッジをノードに重ねる
+	a.analyzeCoverage()
+
+	// 記録した実行経路をノードの列に変換
+	if err := a.analyzeTraces(); err != nil {
+		return nil, err
+	}
+
 	return a.functions, nil
 }
 
+// CheckFailures は check モードで失敗とする検出結果（レイヤールールの違反など）を返す
+func (a *Analyzer) CheckFailures() []string {
+	return a.checkFailures
+}
+
 func (a *Analyzer) parseFile(fileName string) error {
 	src, err := os.ReadFile(fileName)
 	if err != nil {
@@ -83,6 +248,7 @@ func (a *Analyzer) parseFile(fileName string) error {
 	}
 
 	a.files[fileName] = file
+	a.sources[fileName] = src
 	return nil
 }
 
@@ -91,8 +257,19 @@ func (a *Analyzer) analyzeFunctionsInFile(fileName string, file *ast.File) {
 
 	ast.Inspect(file, func(n ast.Node) bool {
 		if funcDecl, ok := n.(*ast.FuncDecl); ok {
+			directives, ok := a.selectedDecls[funcDecl]
+			if !ok {
+				return false
+			}
 			funcInfo := a.analyzeSingleFunction(packageName, fileName, funcDecl)
-			a.functions[funcInfo.FullName] = funcInfo
+			funcInfo.EntryPoint = directives.entrypoint
+			funcInfo.Tags = directives.tags
+			if _, ok := a.functions[funcInfo.ID]; ok {
+				// init やビルドタグで切り替える関数など、同じパッケージに同じ名前の関数があればファイル名で区別する
+				funcInfo.ID += "@" + filepath.Base(fileName)
+			}
+			a.functions[funcInfo.ID] = funcInfo
+			a.functionsByDecl[funcDecl] = funcInfo
 		}
 		return true
 	})
@@ -100,38 +277,83 @@ func (a *Analyzer) analyzeFunctionsInFile(fileName string, file *ast.File) {
 
 func (a *Analyzer) analyzeSingleFunction(packageName, fileName string, funcDecl *ast.FuncDecl) *FunctionInfo {
 	receiverType := ""
+	var receiverParams []string
 	if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
 		receiverType = a.extractReceiverType(funcDecl.Recv.List[0].Type)
+		receiverParams = receiverTypeParams(funcDecl.Recv.List[0].Type)
 	}
 
 	fullName := a.buildFullName(packageName, receiverType, funcDecl.Name.Name)
 
 	// Mermaidコード生成（mermaid_oldベース）
-	mermaidCode := a.generateMermaidCode(funcDecl)
+	chart := a.generateMermaidCode(funcDecl)
 
 	// 関数呼び出しを抽出
 	calledFunctions := a.extractFunctionCalls(funcDecl)
 
+	module := ""
+	if m := a.moduleOfDir(filepath.Dir(fileName)); m != nil {
+		module = m.Path
+	}
+	packagePath := a.importPathOf(filepath.Dir(fileName))
+	if packagePath == "" {
+		packagePath = filepath.ToSlash(filepath.Dir(fileName))
+	}
+
 	return &FunctionInfo{
-		PackageName:     packageName,
-		FileName:        fileName,
-		FunctionName:    funcDecl.Name.Name,
-		FullName:        fullName,
-		ReceiverType:    receiverType,
-		MermaidCode:     mermaidCode,
-		CalledFunctions: calledFunctions,
-		Comments:        a.extractComments(funcDecl),
+		ID:                   a.buildFullName(packagePath, receiverType, funcDecl.Name.Name),
+		Module:               module,
+		PackagePath:          packagePath,
+		PackageName:          packageName,
+		FileName:             fileName,
+		FunctionName:         funcDecl.Name.Name,
+		FullName:             fullName,
+		ReceiverType:         receiverType,
+		ReceiverTypeParams:   receiverParams,
+		TypeParams:           a.typeParamsOf(funcDecl.Type.TypeParams),
+		MermaidCode:          chart.mermaidCode,
+		HappyPathMermaidCode: chart.happyPathCode,
+		CommentMermaidCode:   chart.commentCode,
+		CommentHappyPathCode: chart.commentHappy,
+		CalledFunctions:      calledFunctions,
+		Identifiers:          a.extractIdentifiers(funcDecl),
+		Comments:             a.extractComments(funcDecl),
+		SourceCode:           a.extractSourceCode(fileName, funcDecl),
+		StartLine:            a.fileSet.Position(funcDecl.Pos()).Line,
+		EndLine:              a.fileSet.Position(funcDecl.End()).Line,
+		Nodes:                a.nodeInfos,
+		Compensations:        chart.compensations,
+		flowchart:            chart,
+	}
+}
+
+// extractSourceCode は関数宣言部分のソースコードを切り出す
+func (a *Analyzer) extractSourceCode(fileName string, funcDecl *ast.FuncDecl) string {
+	src, ok := a.sources[fileName]
+	if !ok {
+		return ""
 	}
+	start := a.fileSet.Position(funcDecl.Pos()).Offset
+	end := a.fileSet.Position(funcDecl.End()).Offset
+	if start < 0 || end > len(src) || start > end {
+		return ""
+	}
+	return string(src[start:end])
 }
 
+// extractReceiverType はレシーバーの型名を返す（ジェネリック型の *Cache[K, V] は Cache）
 func (a *Analyzer) extractReceiverType(expr ast.Expr) string {
 	switch t := expr.(type) {
 	case *ast.Ident:
 		return t.Name
 	case *ast.StarExpr:
-		if ident, ok := t.X.(*ast.Ident); ok {
-			return ident.Name
-		}
+		return a.extractReceiverType(t.X)
+	case *ast.ParenExpr:
+		return a.extractReceiverType(t.X)
+	case *ast.IndexExpr:
+		return a.extractReceiverType(t.X)
+	case *ast.IndexListExpr:
+		return a.extractReceiverType(t.X)
 	}
 	return ""
 }
@@ -147,6 +369,9 @@ func (a *Analyzer) extractComments(funcDecl *ast.FuncDecl) string {
 	if funcDecl.Doc != nil {
 		var comments []string
 		for _, comment := range funcDecl.Doc.List {
+			if strings.HasPrefix(comment.Text, directivePrefix) {
+				continue
+			}
 			text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
 			if text != "" {
 				comments = append(comments, text)
@@ -174,6 +399,20 @@ func (a *Analyzer) extractFunctionCalls(funcDecl *ast.FuncDecl) []string {
 	return calls
 }
 
+// extractIdentifiers は関数本体で使っている識別子を重複なく返す（全文検索用）
+func (a *Analyzer) extractIdentifiers(funcDecl *ast.FuncDecl) []string {
+	var identifiers []string
+	seen := make(map[string]bool)
+	ast.Inspect(funcDecl, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Name != "_" && !seen[ident.Name] {
+			identifiers = append(identifiers, ident.Name)
+			seen[ident.Name] = true
+		}
+		return true
+	})
+	return identifiers
+}
+
 func (a *Analyzer) extractCallName(call *ast.CallExpr) string {
 	return a.extractExpressionName(call.Fun)
 }
@@ -189,35 +428,90 @@ func (a *Analyzer) extractExpressionName(expr ast.Expr) string {
 			return base + "." + e.Sel.Name
 		}
 		return e.Sel.Name
+	case *ast.IndexExpr:
+		// ジェネリック関数の呼び出しは型引数を除いた名前にする（Map[int](xs) -> Map）
+		if a.isGenericFunc(e.X) {
+			return a.extractExpressionName(e.X)
+		}
+	case *ast.IndexListExpr:
+		if a.isGenericFunc(e.X) {
+			return a.extractExpressionName(e.X)
+		}
 	}
 	return ""
 }
 
 func (a *Analyzer) buildCallGraph() {
-	// 呼び出しグラフの構築（将来の拡張用）
-	for _, funcInfo := range a.functions {
+	// 型情報から呼び出し先の関数を解決する
+	for funcDecl, funcInfo := range a.functionsByDecl {
+		funcInfo.CallTargets = make(map[string][]string)
+		if funcDecl.Body != nil {
+			ast.Inspect(funcDecl.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				callName := a.extractCallName(call)
+				if callName == "" {
+					return true
+				}
+				for _, target := range a.resolveCallTargets(call) {
+					callee := a.functionOf(target)
+					if callee != nil && !containsString(funcInfo.CallTargets[callName], callee.ID) {
+						funcInfo.CallTargets[callName] = append(funcInfo.CallTargets[callName], callee.ID)
+					}
+				}
+				return true
+			})
+		}
+
 		for _, calledFunc := range funcInfo.CalledFunctions {
 			// 呼び出し関係の記録
 			if a.config.Verbose {
-				fmt.Printf("呼び出し: %s -> %s\n", funcInfo.FullName, calledFunc)
+				if targets := funcInfo.CallTargets[calledFunc]; len(targets) > 0 {
+					fmt.Printf("呼び出し: %s -> %s (%s)\n", funcInfo.FullName, calledFunc, strings.Join(targets, ", "))
+				} else {
+					fmt.Printf("呼び出し: %s -> %s\n", funcInfo.FullName, calledFunc)
+				}
 			}
 		}
 	}
 }
 
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
 // mermaid_oldベースのMermaid生成ロジック
-func (a *Analyzer) generateMermaidCode(funcDecl *ast.FuncDecl) string {
+func (a *Analyzer) generateMermaidCode(funcDecl *ast.FuncDecl) *flowchart {
 	var nodes []string
 	var edges []string
 	edgeSet := make(map[string]bool)
 	nodeCounter := 0
 
-	// 各関数の生成時にfunctionCallNodesをリセット
+	// 各関数の生成時にfunctionCallNodesとnodeInfosをリセット
 	a.functionCallNodes = make(map[string]string)
+	a.nodeInfos = make(map[string]*NodeInfo)
+	a.stmtNodes = make(map[ast.Node]string)
+	a.currentFunc = funcDecl
+	a.errorBranch = ""
+	a.currentGroup = ""
 
+	// エラー分岐の解析中に生成されたノードはエラー経路として記録する
 	genNodeID := func() string {
 		nodeCounter++
-		return fmt.Sprintf("N%d", nodeCounter)
+		nodeID := fmt.Sprintf("N%d", nodeCounter)
+		a.nodeInfos[nodeID] = &NodeInfo{
+			ErrorPath:   a.errorBranch != "",
+			ErrorBranch: a.errorBranch,
+			Group:       a.currentGroup,
+		}
+		return nodeID
 	}
 
 	startID := genNodeID()
@@ -227,12 +521,28 @@ func (a *Analyzer) generateMermaidCode(funcDecl *ast.FuncDecl) string {
 	}
 
 	nodes = append(nodes, fmt.Sprintf("%s([\"`**%s%s**`\"])", startID, structName, funcDecl.Name.Name))
+	if funcDecl.Body != nil {
+		a.recordNodeLines(startID, funcDecl.Pos(), funcDecl.Body.Lbrace)
+	}
 
 	if funcDecl.Body != nil {
 		a.parseBlockStmt(funcDecl.Body, startID, &nodes, &edges, edgeSet, genNodeID, false)
 	}
 
-	return a.formatMermaidOutput(nodes, edges)
+	// 補償処理の漏れをノードに記録してから出力する
+	compensations := a.analyzeCompensations(funcDecl)
+	commentNodes, commentEdges := a.commentOnlyGraph(nodes, edges)
+
+	return &flowchart{
+		nodes:         nodes,
+		edges:         edges,
+		callNodes:     a.functionCallNodes,
+		mermaidCode:   a.formatMermaidOutput(nodes, edges),
+		happyPathCode: a.formatHappyPathMermaidOutput(nodes, edges),
+		commentCode:   a.formatMermaidOutput(commentNodes, commentEdges),
+		commentHappy:  a.formatHappyPathMermaidOutput(commentNodes, commentEdges),
+		compensations: compensations,
+	}
 }
 
 func (a *Analyzer) parseBlockStmt(
@@ -244,12 +554,19 @@ func (a *Analyzer) parseBlockStmt(
 	genNodeID func() string,
 	suppressInitialEdge bool,
 ) string {
+	// //logic:group はブロックの終わりまで有効
+	outerGroup := a.currentGroup
+	defer func() { a.currentGroup = outerGroup }()
+
 	firstNodeID := ""
 
 	for _, stmt := range block.List {
-		// コメントの取得
-		comments := a.getComments(stmt)
-		commentStr := strings.Join(comments, "\\n")
+		// コメントとディレクティブの取得
+		annotation := a.annotationOf(stmt)
+		if annotation.hide {
+			continue
+		}
+		a.enterGroup(annotation)
 
 		switch s := stmt.(type) {
 		case *ast.AssignStmt, *ast.ExprStmt, *ast.ReturnStmt:
@@ -258,27 +575,21 @@ func (a *Analyzer) parseBlockStmt(
 			switch st := s.(type) {
 			case *ast.AssignStmt:
 				exprStr := a.stmtToString(st)
-				if commentStr != "" {
-					exprStr = commentStr + "\\n" + exprStr
-				}
+				exprStr = annotation.text(exprStr)
 				label = a.escapeString(exprStr)
 				*nodes = append(*nodes, fmt.Sprintf("%s[\"%s\"]", nodeID, label))
 				// 関数呼び出しを検出して記録
 				a.detectAndRecordFunctionCalls(st, nodeID)
 			case *ast.ExprStmt:
 				exprStr := a.exprToString(st.X)
-				if commentStr != "" {
-					exprStr = commentStr + "\\n" + exprStr
-				}
+				exprStr = annotation.text(exprStr)
 				label = a.escapeString(exprStr)
 				*nodes = append(*nodes, fmt.Sprintf("%s[\"%s\"]", nodeID, label))
 				// 関数呼び出しを検出して記録
 				a.detectAndRecordFunctionCalls(st, nodeID)
 			case *ast.ReturnStmt:
 				exprStr := a.stmtToString(st)
-				if commentStr != "" {
-					exprStr = commentStr + "\\n" + exprStr
-				}
+				exprStr = annotation.text(exprStr)
 				label = a.escapeString(exprStr)
 				*nodes = append(*nodes, fmt.Sprintf("%s([\"%s\"])", nodeID, label))
 				// 関数呼び出しを検出して記録
@@ -289,6 +600,10 @@ func (a *Analyzer) parseBlockStmt(
 				*nodes = append(*nodes, fmt.Sprintf("%s((\"終了\"))", endNodeID))
 				a.addEdge(nodeID, endNodeID, edges, edgeSet)
 			}
+			a.recordNodeLines(nodeID, s.Pos(), s.End())
+			a.classifyStatement(nodeID, s)
+			a.annotateNode(nodeID, annotation)
+			a.stmtNodes[s] = nodeID
 			if !suppressInitialEdge {
 				a.addEdge(currentID, nodeID, edges, edgeSet)
 			}
@@ -300,13 +615,13 @@ func (a *Analyzer) parseBlockStmt(
 			}
 			currentID = nodeID
 		case *ast.IfStmt:
-			cond := a.exprToString(s.Cond)
-			if commentStr != "" {
-				cond = commentStr + "\\n" + cond
-			}
+			cond := a.condToString(s.Cond)
+			cond = annotation.text(cond)
 			condID := genNodeID()
 			label := a.escapeString(cond)
 			*nodes = append(*nodes, fmt.Sprintf("%s{{\"%s\"}}", condID, label))
+			a.recordNodeLines(condID, s.Pos(), s.Body.Lbrace)
+			a.annotateNode(condID, annotation)
 			if !suppressInitialEdge {
 				a.addEdge(currentID, condID, edges, edgeSet)
 			}
@@ -321,7 +636,9 @@ func (a *Analyzer) parseBlockStmt(
 			}
 
 			// "Yes" 分岐 - ブロックの最初のノードに接続し、最後のノードIDを取得
+			leaveYes := a.enterYesBranch(condID, s)
 			yesFirstID, yesLastID := a.parseBlockStmtWithFirstLast(s.Body, condID, nodes, edges, edgeSet, genNodeID, true)
+			leaveYes()
 			if yesFirstID != "" {
 				a.addLabeledEdge(condID, "Yes", yesFirstID, edges, edgeSet)
 			}
@@ -371,12 +688,12 @@ func (a *Analyzer) parseBlockStmt(
 			value := a.exprToString(s.Value)
 			x := a.exprToString(s.X)
 			rangeLabel := fmt.Sprintf("for %s, %s := range %s", key, value, x)
-			if commentStr != "" {
-				rangeLabel = commentStr + "\\n" + rangeLabel
-			}
+			rangeLabel = annotation.text(rangeLabel)
 			rangeID := genNodeID()
 			label := a.escapeString(rangeLabel)
 			*nodes = append(*nodes, fmt.Sprintf("%s{{\"%s\"}}", rangeID, label))
+			a.recordNodeLines(rangeID, s.Pos(), s.Body.Lbrace)
+			a.annotateNode(rangeID, annotation)
 			if !suppressInitialEdge {
 				a.addEdge(currentID, rangeID, edges, edgeSet)
 			}
@@ -415,14 +732,21 @@ func (a *Analyzer) parseBlockStmtWithFirstLast(
 		return "", currentID
 	}
 
+	// //logic:group はブロックの終わりまで有効
+	outerGroup := a.currentGroup
+	defer func() { a.currentGroup = outerGroup }()
+
 	firstNodeID := ""
 	lastNodeID := currentID
 	hasReturn := false
 
 	for _, stmt := range block.List {
-		// コメントの取得
-		comments := a.getComments(stmt)
-		commentStr := strings.Join(comments, "\\n")
+		// コメントとディレクティブの取得
+		annotation := a.annotationOf(stmt)
+		if annotation.hide {
+			continue
+		}
+		a.enterGroup(annotation)
 
 		switch s := stmt.(type) {
 		case *ast.AssignStmt, *ast.ExprStmt, *ast.ReturnStmt:
@@ -431,27 +755,21 @@ func (a *Analyzer) parseBlockStmtWithFirstLast(
 			switch st := s.(type) {
 			case *ast.AssignStmt:
 				exprStr := a.stmtToString(st)
-				if commentStr != "" {
-					exprStr = commentStr + "\\n" + exprStr
-				}
+				exprStr = annotation.text(exprStr)
 				label = a.escapeString(exprStr)
 				*nodes = append(*nodes, fmt.Sprintf("%s[\"%s\"]", nodeID, label))
 				// 関数呼び出しを検出して記録
 				a.detectAndRecordFunctionCalls(st, nodeID)
 			case *ast.ExprStmt:
 				exprStr := a.exprToString(st.X)
-				if commentStr != "" {
-					exprStr = commentStr + "\\n" + exprStr
-				}
+				exprStr = annotation.text(exprStr)
 				label = a.escapeString(exprStr)
 				*nodes = append(*nodes, fmt.Sprintf("%s[\"%s\"]", nodeID, label))
 				// 関数呼び出しを検出して記録
 				a.detectAndRecordFunctionCalls(st, nodeID)
 			case *ast.ReturnStmt:
 				exprStr := a.stmtToString(st)
-				if commentStr != "" {
-					exprStr = commentStr + "\\n" + exprStr
-				}
+				exprStr = annotation.text(exprStr)
 				label = a.escapeString(exprStr)
 				*nodes = append(*nodes, fmt.Sprintf("%s([\"%s\"])", nodeID, label))
 				// 関数呼び出しを検出して記録
@@ -463,6 +781,10 @@ func (a *Analyzer) parseBlockStmtWithFirstLast(
 				*nodes = append(*nodes, fmt.Sprintf("%s((\"終了\"))", endNodeID))
 				a.addEdge(nodeID, endNodeID, edges, edgeSet)
 			}
+			a.recordNodeLines(nodeID, s.Pos(), s.End())
+			a.classifyStatement(nodeID, s)
+			a.annotateNode(nodeID, annotation)
+			a.stmtNodes[s] = nodeID
 			if !suppressInitialEdge && lastNodeID != "" {
 				a.addEdge(lastNodeID, nodeID, edges, edgeSet)
 			}
@@ -474,13 +796,13 @@ func (a *Analyzer) parseBlockStmtWithFirstLast(
 			}
 			lastNodeID = nodeID
 		case *ast.IfStmt:
-			cond := a.exprToString(s.Cond)
-			if commentStr != "" {
-				cond = commentStr + "\\n" + cond
-			}
+			cond := a.condToString(s.Cond)
+			cond = annotation.text(cond)
 			condID := genNodeID()
 			label := a.escapeString(cond)
 			*nodes = append(*nodes, fmt.Sprintf("%s{{\"%s\"}}", condID, label))
+			a.recordNodeLines(condID, s.Pos(), s.Body.Lbrace)
+			a.annotateNode(condID, annotation)
 			if !suppressInitialEdge && lastNodeID != "" {
 				a.addEdge(lastNodeID, condID, edges, edgeSet)
 			}
@@ -495,7 +817,9 @@ func (a *Analyzer) parseBlockStmtWithFirstLast(
 			}
 
 			// "Yes" 分岐 - ブロックの最初のノードに接続
+			leaveYes := a.enterYesBranch(condID, s)
 			yesFirstID, _ := a.parseBlockStmtWithFirstLast(s.Body, condID, nodes, edges, edgeSet, genNodeID, true)
+			leaveYes()
 			if yesFirstID != "" {
 				a.addLabeledEdge(condID, "Yes", yesFirstID, edges, edgeSet)
 			}
@@ -516,12 +840,12 @@ func (a *Analyzer) parseBlockStmtWithFirstLast(
 			value := a.exprToString(s.Value)
 			x := a.exprToString(s.X)
 			rangeLabel := fmt.Sprintf("for %s, %s := range %s", key, value, x)
-			if commentStr != "" {
-				rangeLabel = commentStr + "\\n" + rangeLabel
-			}
+			rangeLabel = annotation.text(rangeLabel)
 			rangeID := genNodeID()
 			label := a.escapeString(rangeLabel)
 			*nodes = append(*nodes, fmt.Sprintf("%s{{\"%s\"}}", rangeID, label))
+			a.recordNodeLines(rangeID, s.Pos(), s.Body.Lbrace)
+			a.annotateNode(rangeID, annotation)
 			if !suppressInitialEdge && lastNodeID != "" {
 				a.addEdge(lastNodeID, rangeID, edges, edgeSet)
 			}
@@ -563,21 +887,23 @@ func (a *Analyzer) parseElseStmt(
 		firstID, _ := a.parseBlockStmtWithFirstLast(stmt, parentID, nodes, edges, edgeSet, genNodeID, true)
 		return firstID
 	case *ast.IfStmt:
-		cond := a.exprToString(stmt.Cond)
-		comments := a.getComments(stmt)
-		if len(comments) > 0 {
-			cond = strings.Join(comments, "\\n") + "\\n" + cond
-		}
+		cond := a.condToString(stmt.Cond)
+		annotation := a.annotationOf(stmt)
+		cond = annotation.text(cond)
 		condID := genNodeID()
 		label := a.escapeString(cond)
 		*nodes = append(*nodes, fmt.Sprintf("%s{{\"%s\"}}", condID, label))
+		a.recordNodeLines(condID, stmt.Pos(), stmt.Body.Lbrace)
+		a.annotateNode(condID, annotation)
 		a.addEdge(parentID, condID, edges, edgeSet)
 
 		// if文の条件式内の関数呼び出しを検出して記録
 		a.detectAndRecordFunctionCallsInExpr(stmt.Cond, condID)
 
 		// Yes分岐も最初のノードに接続するように修正
+		leaveYes := a.enterYesBranch(condID, stmt)
 		yesFirstID, _ := a.parseBlockStmtWithFirstLast(stmt.Body, condID, nodes, edges, edgeSet, genNodeID, true)
+		leaveYes()
 		if yesFirstID != "" {
 			a.addLabeledEdge(condID, "Yes", yesFirstID, edges, edgeSet)
 		}
@@ -591,12 +917,12 @@ func (a *Analyzer) parseElseStmt(
 	default:
 		elseID := genNodeID()
 		stmtStr := a.stmtToString(stmt)
-		comments := a.getComments(stmt)
-		if len(comments) > 0 {
-			stmtStr = strings.Join(comments, "\\n") + "\\n" + stmtStr
-		}
+		annotation := a.annotationOf(stmt)
+		stmtStr = annotation.text(stmtStr)
 		label := a.escapeString(stmtStr)
 		*nodes = append(*nodes, fmt.Sprintf("%s[\"%s\"]", elseID, label))
+		a.recordNodeLines(elseID, stmt.Pos(), stmt.End())
+		a.annotateNode(elseID, annotation)
 		a.addEdge(parentID, elseID, edges, edgeSet)
 		return elseID
 	}
@@ -616,14 +942,14 @@ func (a *Analyzer) parseElseStmtWithFirstLast(
 	case *ast.BlockStmt:
 		return a.parseBlockStmtWithFirstLast(stmt, parentID, nodes, edges, edgeSet, genNodeID, suppressInitialEdge)
 	case *ast.IfStmt:
-		cond := a.exprToString(stmt.Cond)
-		comments := a.getComments(stmt)
-		if len(comments) > 0 {
-			cond = strings.Join(comments, "\\n") + "\\n" + cond
-		}
+		cond := a.condToString(stmt.Cond)
+		annotation := a.annotationOf(stmt)
+		cond = annotation.text(cond)
 		condID := genNodeID()
 		label := a.escapeString(cond)
 		*nodes = append(*nodes, fmt.Sprintf("%s{{\"%s\"}}", condID, label))
+		a.recordNodeLines(condID, stmt.Pos(), stmt.Body.Lbrace)
+		a.annotateNode(condID, annotation)
 		if !suppressInitialEdge {
 			a.addEdge(parentID, condID, edges, edgeSet)
 		}
@@ -632,7 +958,9 @@ func (a *Analyzer) parseElseStmtWithFirstLast(
 		a.detectAndRecordFunctionCallsInExpr(stmt.Cond, condID)
 
 		// Yes分岐の処理
+		leaveYes := a.enterYesBranch(condID, stmt)
 		yesFirstID, _ := a.parseBlockStmtWithFirstLast(stmt.Body, condID, nodes, edges, edgeSet, genNodeID, true)
+		leaveYes()
 		if yesFirstID != "" {
 			a.addLabeledEdge(condID, "Yes", yesFirstID, edges, edgeSet)
 		}
@@ -651,12 +979,12 @@ func (a *Analyzer) parseElseStmtWithFirstLast(
 	default:
 		elseID := genNodeID()
 		stmtStr := a.stmtToString(stmt)
-		comments := a.getComments(stmt)
-		if len(comments) > 0 {
-			stmtStr = strings.Join(comments, "\\n") + "\\n" + stmtStr
-		}
+		annotation := a.annotationOf(stmt)
+		stmtStr = annotation.text(stmtStr)
 		label := a.escapeString(stmtStr)
 		*nodes = append(*nodes, fmt.Sprintf("%s[\"%s\"]", elseID, label))
+		a.recordNodeLines(elseID, stmt.Pos(), stmt.End())
+		a.annotateNode(elseID, annotation)
 		if !suppressInitialEdge {
 			a.addEdge(parentID, elseID, edges, edgeSet)
 		}
@@ -665,40 +993,82 @@ func (a *Analyzer) parseElseStmtWithFirstLast(
 }
 
 func (a *Analyzer) exprToString(expr ast.Expr) string {
+	return a.formatExpr(expr, nil)
+}
+
+// condToString は条件ノードに表示する条件式を返す
+// AnnotateConstants が有効な場合は、定数の後ろに値（列挙値は宣言のコメント）を併記する
+func (a *Analyzer) condToString(expr ast.Expr) string {
+	if !a.config.AnnotateConstants {
+		return a.exprToString(expr)
+	}
+	return a.formatExpr(expr, a.constAnnotation)
+}
+
+// formatExpr は式を文字列にする。annotate が nil でなければ、識別子ごとに返された注記を後ろに付ける
+func (a *Analyzer) formatExpr(expr ast.Expr, annotate func(*ast.Ident) string) string {
 	switch e := expr.(type) {
 	case *ast.BinaryExpr:
-		return a.exprToString(e.X) + " " + e.Op.String() + " " + a.exprToString(e.Y)
+		return a.formatExpr(e.X, annotate) + " " + e.Op.String() + " " + a.formatExpr(e.Y, annotate)
 	case *ast.Ident:
+		if annotate != nil {
+			return e.Name + annotate(e)
+		}
 		return e.Name
 	case *ast.BasicLit:
 		return e.Value
 	case *ast.CallExpr:
-		return a.exprToString(e.Fun) + "(" + a.argsToString(e.Args) + ")"
+		return a.formatExpr(e.Fun, annotate) + "(" + a.formatArgs(e.Args, annotate) + ")"
 	case *ast.SelectorExpr:
-		return a.exprToString(e.X) + "." + e.Sel.Name
+		if annotate != nil {
+			return a.formatExpr(e.X, annotate) + "." + e.Sel.Name + annotate(e.Sel)
+		}
+		return a.formatExpr(e.X, annotate) + "." + e.Sel.Name
 	case *ast.StarExpr:
-		return "*" + a.exprToString(e.X)
+		return "*" + a.formatExpr(e.X, annotate)
 	case *ast.UnaryExpr:
-		return e.Op.String() + a.exprToString(e.X)
+		return e.Op.String() + a.formatExpr(e.X, annotate)
 	case *ast.ParenExpr:
-		return "(" + a.exprToString(e.X) + ")"
+		return "(" + a.formatExpr(e.X, annotate) + ")"
 	case *ast.TypeAssertExpr:
-		return a.exprToString(e.X) + ".(" + a.exprToString(e.Type) + ")"
+		return a.formatExpr(e.X, annotate) + ".(" + a.formatExpr(e.Type, annotate) + ")"
 	case *ast.IndexExpr:
-		return a.exprToString(e.X) + "[" + a.exprToString(e.Index) + "]"
+		return a.formatExpr(e.X, annotate) + "[" + a.formatExpr(e.Index, annotate) + "]"
+	case *ast.IndexListExpr:
+		// 型引数を複数指定したジェネリック関数・型のインスタンス化（Map[K, V] など）
+		return a.formatExpr(e.X, annotate) + "[" + a.formatArgs(e.Indices, annotate) + "]"
 	default:
 		return ""
 	}
 }
 
 func (a *Analyzer) argsToString(args []ast.Expr) string {
+	return a.formatArgs(args, nil)
+}
+
+func (a *Analyzer) formatArgs(args []ast.Expr, annotate func(*ast.Ident) string) string {
 	var argStrs []string
 	for _, arg := range args {
-		argStrs = append(argStrs, a.exprToString(arg))
+		argStrs = append(argStrs, a.formatExpr(arg, annotate))
 	}
 	return strings.Join(argStrs, ", ")
 }
 
+// constAnnotation は定数を参照している識別子に付ける注記を返す
+// モジュール内の型付き定数（ステータスなどの列挙値）は宣言のコメントを、それ以外は値を表示する
+func (a *Analyzer) constAnnotation(ident *ast.Ident) string {
+	c, ok := a.info.Uses[ident].(*types.Const)
+	if !ok || c.Pkg() == nil {
+		return ""
+	}
+	if _, isNamed := c.Type().(*types.Named); isNamed && a.isModulePackage(c.Pkg().Path()) {
+		if label := a.constComments(c.Pkg().Path())[c.Pos()].label(); label != "" {
+			return " (" + strings.SplitN(label, "\n", 2)[0] + ")"
+		}
+	}
+	return " (" + c.Val().String() + ")"
+}
+
 func (a *Analyzer) stmtToString(stmt ast.Stmt) string {
 	switch s := stmt.(type) {
 	case *ast.AssignStmt:
@@ -726,7 +1096,7 @@ func (a *Analyzer) stmtToString(stmt ast.Stmt) string {
 func (a *Analyzer) detectAndRecordFunctionCalls(stmt ast.Stmt, nodeID string) {
 	ast.Inspect(stmt, func(n ast.Node) bool {
 		if call, ok := n.(*ast.CallExpr); ok {
-			if callName := a.extractCallName(call); callName != "" {
+			if callName := a.extractCallName(call); callName != "" && !a.isFilteredCall(call) {
 				// 関数呼び出しをfunctionCallNodesに記録
 				a.functionCallNodes[nodeID] = callName
 			}
@@ -741,8 +1111,10 @@ func (a *Analyzer) detectAndRecordFunctionCallsInExpr(expr ast.Expr, nodeID stri
 	ast.Inspect(expr, func(n ast.Node) bool {
 		if call, ok := n.(*ast.CallExpr); ok {
 			if callName := a.extractCallName(call); callName != "" {
-				// 関数呼び出しをfunctionCallNodesに記録
-				a.functionCallNodes[nodeID] = callName
+				// 関数呼び出しをfunctionCallNodesに記録（ドキュメント化から除外した関数はクリックできないよう記録しない）
+				if !a.isFilteredCall(call) {
+					a.functionCallNodes[nodeID] = callName
+				}
 				// 最初の関数呼び出しを記録したら、それ以降の検索を停止
 				return false
 			}
@@ -751,6 +1123,117 @@ func (a *Analyzer) detectAndRecordFunctionCallsInExpr(expr ast.Expr, nodeID stri
 	})
 }
 
+// recordNodeLines はノードIDに対応するソースの行範囲を記録する
+func (a *Analyzer) recordNodeLines(nodeID string, from, to token.Pos) {
+	node := a.nodeInfos[nodeID]
+	node.StartLine = a.fileSet.Position(from).Line
+	node.EndLine = a.fileSet.Position(to).Line
+}
+
+// enterYesBranch はif文のYes分岐を解析する前に呼び出し、条件ノードを分類する
+// Yes分岐がエラー処理であれば、分岐内で生成されるノードをエラー経路として扱う
+// 戻り値の関数を呼び出すと分岐に入る前の状態に戻る
+func (a *Analyzer) enterYesBranch(condID string, ifStmt *ast.IfStmt) func() {
+	a.nodeInfos[condID].ErrorCheck = a.isErrorCheck(ifStmt.Cond)
+
+	prev := a.errorBranch
+	if prev == "" && (a.nodeInfos[condID].ErrorCheck || a.returnsError(ifStmt.Body)) {
+		a.errorBranch = condID
+	}
+	return func() {
+		a.errorBranch = prev
+	}
+}
+
+// classifyStatement はエラーを返すreturn文やエラー型の生成を含む文をエラー経路に分類する
+func (a *Analyzer) classifyStatement(nodeID string, stmt ast.Stmt) {
+	info := a.nodeInfos[nodeID]
+	if ret, ok := stmt.(*ast.ReturnStmt); ok && a.isErrorReturn(ret) {
+		info.ErrorReturn = true
+		info.ErrorPath = true
+	}
+	if a.constructsError(stmt) {
+		info.ErrorPath = true
+	}
+}
+
+// isErrorCheck は条件式が err != nil 形式のエラー判定を含むかどうかを返す
+func (a *Analyzer) isErrorCheck(cond ast.Expr) bool {
+	found := false
+	ast.Inspect(cond, func(n ast.Node) bool {
+		bin, ok := n.(*ast.BinaryExpr)
+		if !ok || bin.Op != token.NEQ {
+			return !found
+		}
+		if (a.isErrorIdent(bin.X) && isNilIdent(bin.Y)) || (isNilIdent(bin.X) && a.isErrorIdent(bin.Y)) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// isErrorIdent はエラー変数と思われる識別子かどうかを返す（err, lastErr など）
+func (a *Analyzer) isErrorIdent(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	return ident.Name == "err" || strings.HasSuffix(ident.Name, "Err")
+}
+
+func isNilIdent(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "nil"
+}
+
+// returnsError はブロック直下にエラーを返すreturn文があるかどうかを返す
+func (a *Analyzer) returnsError(block *ast.BlockStmt) bool {
+	for _, stmt := range block.List {
+		if ret, ok := stmt.(*ast.ReturnStmt); ok && a.isErrorReturn(ret) {
+			return true
+		}
+	}
+	return false
+}
+
+// isErrorReturn はreturn文がnilでないエラーを返すかどうかを返す
+// 関数の最後の戻り値の型が error の場合のみ対象とする
+func (a *Analyzer) isErrorReturn(ret *ast.ReturnStmt) bool {
+	if a.currentFunc == nil || a.currentFunc.Type.Results == nil || len(ret.Results) == 0 {
+		return false
+	}
+	results := a.currentFunc.Type.Results.List
+	lastType, ok := results[len(results)-1].Type.(*ast.Ident)
+	if !ok || lastType.Name != "error" {
+		return false
+	}
+	return !isNilIdent(ret.Results[len(ret.Results)-1])
+}
+
+// constructsError は entity.XxxError{} などのエラー型の生成や errors.New / fmt.Errorf を含むかどうかを返す
+func (a *Analyzer) constructsError(node ast.Node) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.CompositeLit:
+			if strings.HasSuffix(a.extractExpressionName(e.Type), "Error") {
+				found = true
+			}
+		case *ast.CallExpr:
+			switch a.extractCallName(e) {
+			case "errors.New", "fmt.Errorf":
+				found = true
+			}
+		case *ast.FuncLit:
+			// 無名関数の中身は対象外
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
 func (a *Analyzer) escapeString(s string) string {
 	s = strings.ReplaceAll(s, `"`, "#quot;")
 	s = strings.ReplaceAll(s, "{", "\\{")
@@ -786,22 +1269,158 @@ func (a *Analyzer) formatMermaidOutput(nodes, edges []string) string {
 		buf.WriteString(fmt.Sprintf("    %s\n", edge))
 	}
 
+	// 出力対象のノードを収集
+	nodeIDs := make([]string, 0, len(nodes))
+	present := make(map[string]bool)
+	for _, node := range nodes {
+		nodeID := nodeIDOf(node)
+		nodeIDs = append(nodeIDs, nodeID)
+		present[nodeID] = true
+	}
+	a.writeNodeGroups(&buf, nodeIDs)
+
 	// 関数呼び出しのクリックイベントを追加
 	for nodeID, functionCall := range a.functionCallNodes {
-		buf.WriteString(fmt.Sprintf("    click %s \"javascript:navigateToFunction('%s')\"\n", nodeID, functionCall))
+		if present[nodeID] {
+			buf.WriteString(fmt.Sprintf("    click %s \"javascript:navigateToFunction('%s')\"\n", nodeID, functionCall))
+		}
 	}
 
+	// エラー判定・エラー経路のスタイルを追加
+	var errorChecks, errorPaths, missingCompensations []string
+	for _, nodeID := range nodeIDs {
+		info, ok := a.nodeInfos[nodeID]
+		if !ok {
+			continue
+		}
+		if info.ErrorCheck {
+			errorChecks = append(errorChecks, nodeID)
+		} else if info.ErrorPath {
+			errorPaths = append(errorPaths, nodeID)
+		}
+		if info.MissingCompensation {
+			missingCompensations = append(missingCompensations, nodeID)
+		}
+	}
+	writeNodeClasses(&buf, errorChecks, errorPaths, missingCompensations)
+
 	return buf.String()
 }
 
+// writeNodeClasses はエラー判定・エラー経路・補償処理の漏れのスタイルを書き出す
+func writeNodeClasses(buf *bytes.Buffer, errorChecks, errorPaths, missingCompensations []string) {
+	buf.WriteString("    classDef errorCheck fill:#fff3cd,stroke:#fd7e14,color:#664d03\n")
+	buf.WriteString("    classDef errorPath fill:#f8d7da,stroke:#dc3545,color:#842029\n")
+	if len(errorChecks) > 0 {
+		buf.WriteString(fmt.Sprintf("    class %s errorCheck\n", strings.Join(errorChecks, ",")))
+	}
+	if len(errorPaths) > 0 {
+		buf.WriteString(fmt.Sprintf("    class %s errorPath\n", strings.Join(errorPaths, ",")))
+	}
+	if len(missingCompensations) > 0 {
+		buf.WriteString("    classDef compensationMissing fill:#f8d7da,stroke:#dc3545,stroke-width:4px,stroke-dasharray:6 3,color:#842029\n")
+		buf.WriteString(fmt.Sprintf("    class %s compensationMissing\n", strings.Join(missingCompensations, ",")))
+	}
+}
+
+// formatHappyPathMermaidOutput はエラー分岐のノードを取り除き、
+// エラーを返す分岐を共通の終了ノードへの「エラー返却」エッジに畳んだMermaidコードを生成する
+func (a *Analyzer) formatHappyPathMermaidOutput(nodes, edges []string) string {
+	const errorEndID = "NE"
+
+	// エラーを返す分岐の条件ノードを収集
+	returningBranches := make(map[string]bool)
+	for _, info := range a.nodeInfos {
+		if info.ErrorBranch != "" && info.ErrorReturn {
+			returningBranches[info.ErrorBranch] = true
+		}
+	}
+
+	isHidden := func(nodeID string) bool {
+		info, ok := a.nodeInfos[nodeID]
+		return ok && info.ErrorBranch != ""
+	}
+
+	var happyNodes []string
+	var collapsed []string
+	for _, node := range nodes {
+		nodeID := nodeIDOf(node)
+		if isHidden(nodeID) {
+			continue
+		}
+		happyNodes = append(happyNodes, node)
+		if returningBranches[nodeID] {
+			collapsed = append(collapsed, nodeID)
+		}
+	}
+
+	var happyEdges []string
+	for _, edge := range edges {
+		fields := strings.Fields(edge)
+		if isHidden(fields[0]) || isHidden(fields[len(fields)-1]) {
+			continue
+		}
+		happyEdges = append(happyEdges, edge)
+	}
+
+	if len(collapsed) > 0 {
+		happyNodes = append(happyNodes, fmt.Sprintf("%s([\"エラー終了\"])", errorEndID))
+		edgeSet := make(map[string]bool)
+		for _, condID := range collapsed {
+			a.addLabeledEdge(condID, "エラー返却", errorEndID, &happyEdges, edgeSet)
+		}
+	}
+
+	code := a.formatMermaidOutput(happyNodes, happyEdges)
+	if len(collapsed) > 0 {
+		code += fmt.Sprintf("    class %s errorPath\n", errorEndID)
+	}
+	return code
+}
+
+// nodeIDOf はノード定義文字列からノードIDを取り出す
+func nodeIDOf(node string) string {
+	for i, r := range node {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return node[:i]
+		}
+	}
+	return node
+}
+
 func (a *Analyzer) getComments(node ast.Node) []string {
 	var comments []string
+	for _, comment := range a.leadingComments(node) {
+		if strings.HasPrefix(comment.Text, directivePrefix) {
+			continue
+		}
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if text != "" {
+			comments = append(comments, text)
+		}
+	}
+	return comments
+}
+
+// getDirectives はノードの直前に書かれた //logic: ディレクティブを接頭辞を除いて返す
+func (a *Analyzer) getDirectives(node ast.Node) []string {
+	var directives []string
+	for _, comment := range a.leadingComments(node) {
+		if strings.HasPrefix(comment.Text, directivePrefix) {
+			directives = append(directives, strings.TrimSpace(strings.TrimPrefix(comment.Text, directivePrefix)))
+		}
+	}
+	return directives
+}
+
+// leadingComments はノードの直前の行に続けて書かれたコメントを返す
+func (a *Analyzer) leadingComments(node ast.Node) []*ast.Comment {
+	var comments []*ast.Comment
 	if node == nil {
 		return comments
 	}
 
 	pos := node.Pos()
-	end := node.End()
 	file := a.fileSet.File(pos)
 	if file == nil {
 		return comments
@@ -820,20 +1439,51 @@ func (a *Analyzer) getComments(node ast.Node) []string {
 		return comments
 	}
 
-	// ノードの直前および直後のコメントを取得
+	// 行の先頭から始まり、ノードの直前の行で終わるコメントを取得
+	// 前の行の行末コメントや、前の文のブロック内の最後のコメント（else if の直前など）は含めない
+	line := file.Line(pos)
+	src := a.sources[file.Name()]
 	for _, cgroup := range astFile.Comments {
-		if cgroup.Pos() <= pos && cgroup.End() <= end && cgroup.End()+3 >= pos {
-			for _, comment := range cgroup.List {
-				text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
-				if text != "" {
-					comments = append(comments, text)
-				}
-			}
+		if cgroup.Pos() >= pos {
+			break
+		}
+		if file.Line(cgroup.End()) != line-1 || !startsOwnLine(file, src, cgroup.Pos()) || enclosedBefore(astFile, cgroup, pos) {
+			continue
 		}
+		comments = append(comments, cgroup.List...)
 	}
 	return comments
 }
 
+// startsOwnLine はコメントが行の先頭（インデントの後）から始まるかどうかを返す
+func startsOwnLine(file *token.File, src []byte, pos token.Pos) bool {
+	start := file.Offset(file.LineStart(file.Line(pos)))
+	offset := file.Offset(pos)
+	if offset > len(src) {
+		return false
+	}
+	return strings.TrimSpace(string(src[start:offset])) == ""
+}
+
+// enclosedBefore はコメントが pos より前に終わる構文（前の文の if のブロックなど）の内側にあるかどうかを返す
+func enclosedBefore(file *ast.File, cgroup *ast.CommentGroup, pos token.Pos) bool {
+	enclosed := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil || enclosed || n.Pos() > cgroup.Pos() || n.End() < cgroup.End() {
+			return false
+		}
+		switch n.(type) {
+		case *ast.CommentGroup, *ast.Comment:
+			return false
+		}
+		if n.End() <= pos {
+			enclosed = true
+		}
+		return !enclosed
+	})
+	return enclosed
+}
+
 func (a *Analyzer) filterFiles(files []string) []string {
 	if len(a.config.ExcludePatterns) == 0 {
 		return files
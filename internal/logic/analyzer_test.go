@@ -0,0 +1,90 @@
+package main
+
+import (
+	"go/ast"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const leadingCommentsSource = `package sample
+
+func f(a, b bool) int {
+	x := 1 // 行末のコメント
+	y := 2
+	// y を更新
+	//logic:label 更新
+	y++
+	if a {
+		x++
+		// if の中の最後のコメント
+	} else if b {
+		y--
+	}
+	/* ブロックコメント */ x--
+
+	// 空行を挟んだコメント
+
+	return x + y
+}
+`
+
+func TestLeadingComments(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "sample.go")
+	if err := os.WriteFile(fileName, []byte(leadingCommentsSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a := NewAnalyzer(&Config{})
+	if err := a.parseFile(fileName); err != nil {
+		t.Fatal(err)
+	}
+	file := a.files[fileName]
+
+	// stmtAt は指定した内容の行で始まる最初の文を返す
+	stmtAt := func(text string) ast.Stmt {
+		line := 0
+		for i, l := range strings.Split(leadingCommentsSource, "\n") {
+			if strings.TrimSpace(l) == text {
+				line = i + 1
+			}
+		}
+		var found ast.Stmt
+		ast.Inspect(file, func(n ast.Node) bool {
+			if stmt, ok := n.(ast.Stmt); ok && found == nil && a.fileSet.Position(stmt.Pos()).Line == line {
+				found = stmt
+			}
+			return found == nil
+		})
+		if found == nil {
+			t.Fatalf("%q の行に文がありません", text)
+		}
+		return found
+	}
+
+	tests := []struct {
+		name       string
+		stmt       string
+		comments   []string
+		directives []string
+	}{
+		{name: "前の行の行末コメントは含めない", stmt: "y := 2"},
+		{name: "直前の行のコメントとディレクティブ", stmt: "y++", comments: []string{"y を更新"}, directives: []string{"label 更新"}},
+		{name: "前のブロック内の最後のコメントは else if に付けない", stmt: "} else if b {"},
+		{name: "ブロックの先頭の文", stmt: "y--"},
+		{name: "同じ行の前のコメントは含めない", stmt: "/* ブロックコメント */ x--"},
+		{name: "空行を挟んだコメントは含めない", stmt: "return x + y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := stmtAt(tt.stmt)
+			if got := a.getComments(stmt); !reflect.DeepEqual(got, tt.comments) {
+				t.Errorf("getComments() = %q, want %q", got, tt.comments)
+			}
+			if got := a.getDirectives(stmt); !reflect.DeepEqual(got, tt.directives) {
+				t.Errorf("getDirectives() = %q, want %q", got, tt.directives)
+			}
+		})
+	}
+}
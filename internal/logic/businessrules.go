@@ -0,0 +1,215 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// ruleReference 定数・マジックナンバーを参照している箇所
+type ruleReference struct {
+	decl *ast.FuncDecl
+	pos  token.Pos
+}
+
+// analyzeBusinessRules は公開定数と条件式中のマジックナンバーを、参照している関数・ノードとともに一覧にする
+func (a *Analyzer) analyzeBusinessRules() {
+	constants := a.businessConstants()
+	references := make(map[*types.Const][]ruleReference)
+	for _, decl := range a.moduleFuncDecls() {
+		ast.Inspect(decl.Body, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			if c, ok := a.info.Uses[ident].(*types.Const); ok && containsConst(constants, c) {
+				references[c] = append(references[c], ruleReference{decl: decl, pos: ident.Pos()})
+			}
+			return true
+		})
+	}
+
+	constantTable := DiagramTable{
+		Title:   "定数",
+		Columns: []string{"定数", "値", "説明", "参照している関数", "ノード", "位置"},
+	}
+	for _, c := range constants {
+		comment := a.constComments(c.Pkg().Path())[c.Pos()]
+		description := comment.doc
+		if comment.line != "" {
+			description = strings.TrimSpace(description + "\n" + comment.line)
+		}
+		head := []DiagramCell{
+			{Text: c.Pkg().Name() + "." + c.Name()},
+			{Text: c.Val().String()},
+			{Text: description},
+		}
+		refs := references[c]
+		if len(refs) == 0 {
+			constantTable.Rows = append(constantTable.Rows, append(head, DiagramCell{Text: "（参照なし）"}, DiagramCell{}, a.positionCell(c.Pos())))
+			continue
+		}
+		for i, ref := range refs {
+			row := head
+			if i > 0 {
+				row = []DiagramCell{{}, {}, {}}
+			}
+			constantTable.Rows = append(constantTable.Rows, append(row, a.referenceCells(ref)...))
+		}
+	}
+
+	magicTable := DiagramTable{
+		Title:   "条件式中の数値リテラル",
+		Columns: []string{"値", "式", "関数", "ノード", "位置"},
+	}
+	for _, decl := range a.moduleFuncDecls() {
+		for _, magic := range a.magicNumbers(decl) {
+			magicTable.Rows = append(magicTable.Rows, append([]DiagramCell{
+				{Text: magic.lit.Value},
+				{Text: a.exprToString(magic.expr)},
+			}, a.referenceCells(ruleReference{decl: decl, pos: magic.lit.Pos()})...))
+		}
+	}
+
+	if len(constantTable.Rows) == 0 && len(magicTable.Rows) == 0 {
+		return
+	}
+	a.diagrams = append(a.diagrams, &Diagram{
+		ID:       "business-rules",
+		Category: "ビジネスルール",
+		Title:    "定数と数値リテラル",
+		Description: "公開されている定数と、条件式や判定関数に直接書かれた数値リテラルの一覧です。" +
+			"参照している関数のノードを選ぶと、フローチャート上の該当箇所を表示します。",
+		Tables: []DiagramTable{constantTable, magicTable},
+	})
+}
+
+// businessConstants はモジュール内で宣言された公開定数を返す
+// 列挙値として使われる型付きの定数（ステータスなど）は対象外
+func (a *Analyzer) businessConstants() []*types.Const {
+	var constants []*types.Const
+	for _, path := range a.modulePackagePaths() {
+		scope := a.packages[path].Scope()
+		for _, name := range scope.Names() {
+			c, ok := scope.Lookup(name).(*types.Const)
+			if !ok || !c.Exported() {
+				continue
+			}
+			if _, isNamed := c.Type().(*types.Named); isNamed {
+				continue
+			}
+			constants = append(constants, c)
+		}
+	}
+	sort.SliceStable(constants, func(i, j int) bool {
+		if constants[i].Pkg() != constants[j].Pkg() {
+			return constants[i].Pkg().Path() < constants[j].Pkg().Path()
+		}
+		return constants[i].Pos() < constants[j].Pos()
+	})
+	return constants
+}
+
+// magicNumber 条件式などに直接書かれた数値リテラル
+type magicNumber struct {
+	lit  *ast.BasicLit
+	expr ast.Expr // リテラルを含む条件式
+}
+
+// magicNumbers は関数の条件式と、bool を返す判定関数の本体に書かれた 0・1 以外の数値リテラルを返す
+func (a *Analyzer) magicNumbers(decl *ast.FuncDecl) []magicNumber {
+	var result []magicNumber
+	collect := func(expr ast.Expr) {
+		if expr == nil {
+			return
+		}
+		ast.Inspect(expr, func(n ast.Node) bool {
+			lit, ok := n.(*ast.BasicLit)
+			if ok && (lit.Kind == token.INT || lit.Kind == token.FLOAT) && !isTrivialNumber(lit.Value) {
+				result = append(result, magicNumber{lit: lit, expr: expr})
+			}
+			return true
+		})
+	}
+
+	predicate := false
+	if fn, ok := a.info.Defs[decl.Name].(*types.Func); ok {
+		predicate = returnsBool(fn)
+	}
+
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.IfStmt:
+			collect(s.Cond)
+		case *ast.ForStmt:
+			collect(s.Cond)
+		case *ast.CaseClause:
+			for _, expr := range s.List {
+				collect(expr)
+			}
+		case *ast.AssignStmt:
+			if predicate {
+				for _, rhs := range s.Rhs {
+					collect(rhs)
+				}
+			}
+		case *ast.ReturnStmt:
+			if predicate {
+				for _, result := range s.Results {
+					collect(result)
+				}
+			}
+		}
+		return true
+	})
+	return result
+}
+
+func isTrivialNumber(value string) bool {
+	switch value {
+	case "0", "1", "0.0", "1.0":
+		return true
+	}
+	return false
+}
+
+// referenceCells は参照箇所を「関数・ノード・位置」のセルにする
+func (a *Analyzer) referenceCells(ref ruleReference) []DiagramCell {
+	function := a.functionCell(ref.decl)
+	node := DiagramCell{}
+	if funcInfo, ok := a.functionsByDecl[ref.decl]; ok {
+		if nodeID := nodeAtLine(funcInfo, a.fileSet.Position(ref.pos).Line); nodeID != "" {
+			node = DiagramCell{Text: nodeID, Function: funcInfo.ID, Node: nodeID}
+		}
+	}
+	return []DiagramCell{function, node, a.positionCell(ref.pos)}
+}
+
+// nodeAtLine は指定した行を含む最も狭い範囲のノードを返す
+func nodeAtLine(funcInfo *FunctionInfo, line int) string {
+	best := ""
+	bestSize := 0
+	for nodeID, info := range funcInfo.Nodes {
+		if info.StartLine == 0 || line < info.StartLine || line > info.EndLine {
+			continue
+		}
+		size := info.EndLine - info.StartLine
+		if best == "" || size < bestSize || (size == bestSize && nodeID < best) {
+			best, bestSize = nodeID, size
+		}
+	}
+	return best
+}
+
+func containsConst(constants []*types.Const, c *types.Const) bool {
+	for _, candidate := range constants {
+		if candidate == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+)
+
+// calleeObject は呼び出し式が指す関数・メソッドのオブジェクトを返す
+// ジェネリック関数のインスタンス化やジェネリック型のメソッドは、宣言された元の関数に解決する
+func (a *Analyzer) calleeObject(fun ast.Expr) *types.Func {
+	var obj *types.Func
+	switch f := fun.(type) {
+	case *ast.Ident:
+		obj, _ = a.info.Uses[f].(*types.Func)
+	case *ast.SelectorExpr:
+		if sel, ok := a.info.Selections[f]; ok {
+			obj, _ = sel.Obj().(*types.Func)
+		} else {
+			obj, _ = a.info.Uses[f.Sel].(*types.Func)
+		}
+	case *ast.ParenExpr:
+		return a.calleeObject(f.X)
+	case *ast.IndexExpr:
+		return a.calleeObject(f.X)
+	case *ast.IndexListExpr:
+		return a.calleeObject(f.X)
+	}
+	if obj == nil {
+		return nil
+	}
+	return obj.Origin()
+}
+
+// resolveCallTargets は呼び出し先の関数宣言を解決する
+// インターフェースのメソッド呼び出しは、モジュール内の実装型のメソッドすべてに解決する
+func (a *Analyzer) resolveCallTargets(call *ast.CallExpr) []*types.Func {
+	fn := a.calleeObject(call.Fun)
+	if fn == nil {
+		return nil
+	}
+
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return []*types.Func{fn}
+	}
+	iface, ok := sig.Recv().Type().Underlying().(*types.Interface)
+	if !ok {
+		return []*types.Func{fn}
+	}
+	return a.implementationsOf(iface, fn.Name())
+}
+
+// implementationsOf はモジュール内でインターフェースを実装する型の、指定メソッドを返す
+func (a *Analyzer) implementationsOf(iface *types.Interface, methodName string) []*types.Func {
+	var methods []*types.Func
+	for _, named := range a.namedTypes() {
+		if types.IsInterface(named) {
+			continue
+		}
+		if !implementsInterface(named, iface) {
+			continue
+		}
+		obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(named), true, named.Obj().Pkg(), methodName)
+		if method, ok := obj.(*types.Func); ok {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// namedTypes は型チェック済みのモジュール内パッケージで宣言された名前付き型を返す
+func (a *Analyzer) namedTypes() []*types.Named {
+	if a.namedTypeCache != nil {
+		return a.namedTypeCache
+	}
+
+	var paths []string
+	for path, pkg := range a.packages {
+		if pkg != nil {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	a.namedTypeCache = []*types.Named{}
+	for _, path := range paths {
+		scope := a.packages[path].Scope()
+		for _, name := range scope.Names() {
+			typeName, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || typeName.IsAlias() {
+				continue
+			}
+			if named, ok := typeName.Type().(*types.Named); ok {
+				a.namedTypeCache = append(a.namedTypeCache, named)
+			}
+		}
+	}
+	return a.namedTypeCache
+}
+
+// functionOf は関数オブジェクトに対応するドキュメント化対象の関数を返す
+func (a *Analyzer) functionOf(fn *types.Func) *FunctionInfo {
+	decl, ok := a.funcDecls[fn]
+	if !ok {
+		return nil
+	}
+	return a.functionsByDecl[decl]
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TypeRef 関数が使用している型と、その型を載せたクラス図
+type TypeRef struct {
+	Name    string `json:"name"`    // entity.Order など
+	Diagram string `json:"diagram"` // クラス図のID
+}
+
+// classDiagramBuilder は1パッケージ分のクラス図を組み立てる
+type classDiagramBuilder struct {
+	analyzer  *Analyzer
+	pkg       *types.Package
+	buf       bytes.Buffer
+	relations []string
+	foreign   map[*types.Named]bool // 他パッケージから参照している型
+}
+
+// classDiagramID はパッケージのクラス図のIDを返す
+func (a *Analyzer) classDiagramID(pkg *types.Package) string {
+	return "class:" + filepath.ToSlash(a.dirOf(pkg.Path()))
+}
+
+// analyzeClassDiagrams はパッケージごとに構造体・インターフェースのクラス図を生成し、
+// ドキュメント化対象の関数に使用している型を記録する
+func (a *Analyzer) analyzeClassDiagrams() {
+	for _, path := range a.modulePackagePaths() {
+		builder := &classDiagramBuilder{analyzer: a, pkg: a.packages[path], foreign: make(map[*types.Named]bool)}
+		if diagram := builder.build(); diagram != nil {
+			a.diagrams = append(a.diagrams, diagram)
+		}
+	}
+
+	for decl, funcInfo := range a.functionsByDecl {
+		funcInfo.UsedTypes = a.usedTypes(decl)
+	}
+}
+
+// classTypes はパッケージで宣言された構造体・インターフェースを宣言順に返す
+func classTypes(pkg *types.Package) []*types.Named {
+	var result []*types.Named
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() {
+			continue
+		}
+		if named, ok := obj.Type().(*types.Named); ok && isClassType(named) {
+			result = append(result, named)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Obj().Pos() < result[j].Obj().Pos() })
+	return result
+}
+
+// isClassType は名前付き型が構造体かインターフェースかどうかを返す
+func isClassType(named *types.Named) bool {
+	switch named.Underlying().(type) {
+	case *types.Struct, *types.Interface:
+		return true
+	}
+	return false
+}
+
+func (b *classDiagramBuilder) build() *Diagram {
+	a := b.analyzer
+	classes := classTypes(b.pkg)
+	if len(classes) == 0 {
+		return nil
+	}
+	docs := a.typeDocs(b.pkg.Path())
+
+	b.buf.WriteString("classDiagram\n")
+	table := DiagramTable{
+		Title:   "型一覧",
+		Columns: []string{"型", "種類", "説明", "実装・埋め込み", "位置"},
+	}
+	for _, named := range classes {
+		kind, related := b.writeClass(named)
+		table.Rows = append(table.Rows, []DiagramCell{
+			{Text: named.Obj().Name() + b.typeParamList(named.TypeParams())},
+			{Text: kind},
+			{Text: docs[named.Obj().Pos()]},
+			{Text: strings.Join(related, ", ")},
+			a.positionCell(named.Obj().Pos()),
+		})
+	}
+
+	// 他パッケージの型は名前だけを置き、そのパッケージのクラス図へリンクする
+	var foreign []*types.Named
+	for named := range b.foreign {
+		foreign = append(foreign, named)
+	}
+	sort.Slice(foreign, func(i, j int) bool { return b.classID(foreign[i]) < b.classID(foreign[j]) })
+	for _, named := range foreign {
+		id := b.classID(named)
+		b.buf.WriteString(fmt.Sprintf("    class %s[\"%s\"]\n", id, typeName(named)))
+		b.buf.WriteString(fmt.Sprintf("    click %s href \"#diagram:%s\"\n", id, a.classDiagramID(named.Obj().Pkg())))
+	}
+
+	for _, relation := range b.relations {
+		b.buf.WriteString("    " + relation + "\n")
+	}
+
+	dir := filepath.ToSlash(a.dirOf(b.pkg.Path()))
+	return &Diagram{
+		ID:          a.classDiagramID(b.pkg),
+		Category:    "クラス図",
+		Title:       dir,
+		Description: b.pkg.Name() + " パッケージの構造体とインターフェースです。フィールドの参照、埋め込み、インターフェースの実装を関連として表示しています。",
+		MermaidCode: b.buf.String(),
+		Tables:      []DiagramTable{table},
+	}
+}
+
+// writeClass はクラス定義と関連を書き出し、種類と実装・埋め込みの一覧を返す
+func (b *classDiagramBuilder) writeClass(named *types.Named) (string, []string) {
+	id := b.classID(named)
+	var related []string
+	b.buf.WriteString(fmt.Sprintf("    class %s {\n", id))
+
+	kind := "構造体"
+	switch t := named.Underlying().(type) {
+	case *types.Interface:
+		kind = "インターフェース"
+		if !t.IsMethodSet() {
+			// ~int | ~float64 などの型集合を持つ、型パラメータの制約にだけ使えるインターフェース
+			kind = "型制約"
+			b.buf.WriteString("        <<constraint>>\n")
+			for _, union := range constraintUnions(t) {
+				for i := 0; i < union.Len(); i++ {
+					b.buf.WriteString("        " + b.termMember(union.Term(i)) + "\n")
+				}
+				related = append(related, "型集合: "+b.unionString(union))
+			}
+		} else {
+			b.buf.WriteString("        <<interface>>\n")
+		}
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			b.buf.WriteString("        " + b.methodMember(t.ExplicitMethod(i)) + "\n")
+		}
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			if embedded, ok := t.EmbeddedType(i).(*types.Named); ok && b.isModuleType(embedded) {
+				b.addRelation(fmt.Sprintf("%s <|-- %s", b.classID(embedded), id))
+				related = append(related, "埋め込み: "+typeName(embedded))
+			}
+		}
+		// 型集合を持つインターフェースとジェネリックなインターフェースは、型引数が決まらないと実装を判定できない
+		if t.NumMethods() > 0 && t.IsMethodSet() && named.TypeParams().Len() == 0 {
+			for _, impl := range b.implementations(t) {
+				b.addRelation(fmt.Sprintf("%s <|.. %s", id, b.classID(impl)))
+				related = append(related, "実装: "+typeName(impl))
+			}
+		}
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			field := t.Field(i)
+			if field.Embedded() {
+				if embedded, ok := derefNamed(field.Type()); ok && b.isModuleType(embedded) && isClassType(embedded) {
+					b.addRelation(fmt.Sprintf("%s <|-- %s", b.classID(embedded), id))
+					related = append(related, "埋め込み: "+typeName(embedded))
+					continue
+				}
+			}
+			b.buf.WriteString(fmt.Sprintf("        %s%s %s\n", visibility(field.Name()), field.Name(), b.typeString(field.Type())))
+			b.fieldRelation(id, field)
+		}
+		for i := 0; i < named.NumMethods(); i++ {
+			b.buf.WriteString("        " + b.methodMember(named.Method(i)) + "\n")
+		}
+		for _, iface := range b.implementedInterfaces(named) {
+			b.addRelation(fmt.Sprintf("%s <|.. %s", b.classID(iface), id))
+			related = append(related, "実装: "+typeName(iface))
+		}
+	}
+	b.buf.WriteString("    }\n")
+	return kind, related
+}
+
+// fieldRelation はフィールドが参照するモジュール内の構造体・インターフェースへの関連を追加する
+// ポインタ・スライス・インターフェースは関連（-->）、値で持つ構造体はコンポジション（*--）で表す
+func (b *classDiagramBuilder) fieldRelation(id string, field *types.Var) {
+	t := field.Type()
+	arrow := "*--"
+	multiplicity := ""
+	for {
+		switch u := t.(type) {
+		case *types.Pointer:
+			t, arrow = u.Elem(), "-->"
+			continue
+		case *types.Slice:
+			t, arrow, multiplicity = u.Elem(), "-->", "\"*\" "
+			continue
+		case *types.Array:
+			t, arrow, multiplicity = u.Elem(), "-->", "\"*\" "
+			continue
+		case *types.Map:
+			t, arrow, multiplicity = u.Elem(), "-->", "\"*\" "
+			continue
+		}
+		break
+	}
+	named, ok := t.(*types.Named)
+	if !ok || !b.isModuleType(named) || !isClassType(named) {
+		return
+	}
+	if types.IsInterface(named) {
+		arrow = "-->"
+	}
+	b.addRelation(fmt.Sprintf("%s %s %s%s : %s", id, arrow, multiplicity, b.classID(named), field.Name()))
+}
+
+// implementations はインターフェースを実装するモジュール内の構造体を返す
+func (b *classDiagramBuilder) implementations(iface *types.Interface) []*types.Named {
+	var result []*types.Named
+	for _, named := range b.analyzer.namedTypes() {
+		if types.IsInterface(named) || !isClassType(named) {
+			continue
+		}
+		if implementsInterface(named, iface) {
+			result = append(result, named)
+		}
+	}
+	return result
+}
+
+// implementedInterfaces は構造体が実装する他パッケージのインターフェースを返す
+// （同じパッケージのインターフェースはインターフェース側で関連を追加する）
+func (b *classDiagramBuilder) implementedInterfaces(named *types.Named) []*types.Named {
+	var result []*types.Named
+	for _, candidate := range b.analyzer.namedTypes() {
+		iface, ok := candidate.Underlying().(*types.Interface)
+		if !ok || iface.NumMethods() == 0 || candidate.Obj().Pkg() == b.pkg || candidate.TypeParams().Len() > 0 {
+			continue
+		}
+		if implementsInterface(named, iface) {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+func (b *classDiagramBuilder) addRelation(relation string) {
+	if !containsString(b.relations, relation) {
+		b.relations = append(b.relations, relation)
+	}
+}
+
+// classID はクラス図上のIDを返す。他パッケージの型は、同じ名前のパッケージと区別できるようインポートパスを付ける
+func (b *classDiagramBuilder) classID(named *types.Named) string {
+	if named.Obj().Pkg() == b.pkg {
+		return named.Obj().Name()
+	}
+	b.foreign[named] = true
+	return sanitizeID(named.Obj().Pkg().Path()) + "_" + named.Obj().Name()
+}
+
+func (b *classDiagramBuilder) isModuleType(named *types.Named) bool {
+	return named.Obj().Pkg() != nil && b.analyzer.isModulePackage(named.Obj().Pkg().Path())
+}
+
+// typeString は同じパッケージの型を修飾せずに型名を返す
+func (b *classDiagramBuilder) typeString(t types.Type) string {
+	return types.TypeString(t, func(pkg *types.Package) string {
+		if pkg == b.pkg {
+			return ""
+		}
+		return pkg.Name()
+	})
+}
+
+// methodMember はメソッドを「+Name(引数の型) 戻り値の型」の形式にする
+func (b *classDiagramBuilder) methodMember(fn *types.Func) string {
+	sig := fn.Type().(*types.Signature)
+	var params, results []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		params = append(params, b.typeString(sig.Params().At(i).Type()))
+	}
+	for i := 0; i < sig.Results().Len(); i++ {
+		results = append(results, b.typeString(sig.Results().At(i).Type()))
+	}
+	member := fmt.Sprintf("%s%s(%s)", visibility(fn.Name()), fn.Name(), strings.Join(params, ", "))
+	if len(results) > 0 {
+		member += " " + strings.Join(results, ", ")
+	}
+	return member
+}
+
+func visibility(name string) string {
+	if token.IsExported(name) {
+		return "+"
+	}
+	return "-"
+}
+
+// derefNamed はポインタを外した名前付き型を返す
+func derefNamed(t types.Type) (*types.Named, bool) {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return named, ok
+}
+
+// typeDocs は型の宣言位置 -> ドキュメントコメントを返す
+func (a *Analyzer) typeDocs(path string) map[token.Pos]string {
+	docs := make(map[token.Pos]string)
+	for _, file := range a.packageFiles[path] {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				doc := typeSpec.Doc
+				if doc == nil && len(genDecl.Specs) == 1 {
+					doc = genDecl.Doc
+				}
+				if doc != nil {
+					docs[typeSpec.Name.Pos()] = strings.TrimSpace(doc.Text())
+				}
+			}
+		}
+	}
+	return docs
+}
+
+// usedTypes は関数のシグネチャと本体で使用しているモジュール内の構造体・インターフェースを返す
+func (a *Analyzer) usedTypes(decl *ast.FuncDecl) []TypeRef {
+	seen := make(map[*types.Named]bool)
+	var refs []TypeRef
+	addType := func(t types.Type) {
+		for {
+			switch u := t.(type) {
+			case *types.Pointer:
+				t = u.Elem()
+				continue
+			case *types.Slice:
+				t = u.Elem()
+				continue
+			case *types.Map:
+				t = u.Elem()
+				continue
+			}
+			break
+		}
+		named, ok := t.(*types.Named)
+		if !ok || seen[named] || named.Obj().Pkg() == nil || !isClassType(named) || !a.isModulePackage(named.Obj().Pkg().Path()) {
+			return
+		}
+		seen[named] = true
+		refs = append(refs, TypeRef{Name: typeName(named), Diagram: a.classDiagramID(named.Obj().Pkg())})
+	}
+
+	ast.Inspect(decl, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		obj := a.info.Uses[ident]
+		if obj == nil {
+			obj = a.info.Defs[ident]
+		}
+		switch o := obj.(type) {
+		case *types.TypeName:
+			addType(o.Type())
+		case *types.Var:
+			addType(o.Type())
+		}
+		return true
+	})
+
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// CompensationPair 獲得処理と、失敗時に実行すべき補償処理の組
+type CompensationPair struct {
+	Acquire    string // 獲得処理のメソッド名（例: ReserveStock）
+	Compensate string // 補償処理のメソッド名（例: ReleaseStock）
+}
+
+// CompensationExit 失敗時の出口（エラーを返すreturn文）ごとの補償処理の実行状況
+type CompensationExit struct {
+	NodeID  string   `json:"nodeId"`
+	Line    int      `json:"line"`
+	Label   string   `json:"label"`
+	Ran     []string `json:"ran"`     // 実行された補償処理
+	Missing []string `json:"missing"` // 実行されていない補償処理
+}
+
+// compensationWalker は関数本体を上から順にたどり、獲得済みの処理と実行済みの補償処理を追跡する
+type compensationWalker struct {
+	analyzer *Analyzer
+	exits    []CompensationExit
+}
+
+// compensationState はある実行経路上の状態
+type compensationState struct {
+	held     []string // 獲得済みで補償されていない獲得処理
+	ran      []string // この経路で実行された補償処理
+	deferred []string // defer で登録された補償処理
+	pending  []string // 直後のエラー判定を待っている獲得処理
+}
+
+func (s compensationState) clone() compensationState {
+	return compensationState{
+		held:     append([]string(nil), s.held...),
+		ran:      append([]string(nil), s.ran...),
+		deferred: append([]string(nil), s.deferred...),
+		pending:  append([]string(nil), s.pending...),
+	}
+}
+
+// analyzeCompensations は失敗時の出口ごとに、実行された補償処理と漏れている補償処理を求める
+// 漏れがあるノードはフローチャート上で警告表示する
+func (a *Analyzer) analyzeCompensations(funcDecl *ast.FuncDecl) []CompensationExit {
+	if len(a.config.CompensationPairs) == 0 || funcDecl.Body == nil {
+		return nil
+	}
+
+	walker := &compensationWalker{analyzer: a}
+	walker.walkBlock(funcDecl.Body.List, compensationState{})
+
+	for _, exit := range walker.exits {
+		if len(exit.Missing) == 0 {
+			continue
+		}
+		if info, ok := a.nodeInfos[exit.NodeID]; ok {
+			info.MissingCompensation = true
+		}
+		if a.config.Verbose {
+			fmt.Printf("警告: 補償処理の漏れ %s:%d (%s が未実行)\n",
+				a.fileSet.Position(funcDecl.Pos()).Filename, exit.Line, strings.Join(exit.Missing, ", "))
+		}
+	}
+	return walker.exits
+}
+
+// walkBlock は文の並びをたどり、return文で終わらなければ末尾の状態を返す
+func (w *compensationWalker) walkBlock(stmts []ast.Stmt, state compensationState) (compensationState, bool) {
+	for _, stmt := range stmts {
+		var terminated bool
+		state, terminated = w.walkStmt(stmt, state)
+		if terminated {
+			return state, true
+		}
+	}
+	return state, false
+}
+
+func (w *compensationWalker) walkStmt(stmt ast.Stmt, state compensationState) (compensationState, bool) {
+	a := w.analyzer
+
+	// エラー判定の直前に獲得した処理は、エラー判定のYes分岐では獲得されていない
+	ifStmt, isIf := stmt.(*ast.IfStmt)
+	if !isIf || !a.isErrorCheck(ifStmt.Cond) {
+		state.held = append(state.held, state.pending...)
+		state.pending = nil
+	}
+
+	switch s := stmt.(type) {
+	case *ast.ReturnStmt:
+		if a.isErrorReturn(s) {
+			w.recordExit(s, state)
+		}
+		return state, true
+	case *ast.IfStmt:
+		if s.Init != nil {
+			state, _ = w.walkStmt(s.Init, state)
+		}
+		if a.isErrorCheck(s.Cond) {
+			// Yes分岐は直前の獲得処理が失敗した経路
+			failure := state.clone()
+			failure.pending = nil
+			w.walkBlock(s.Body.List, failure)
+
+			state.held = append(state.held, state.pending...)
+			state.pending = nil
+			if s.Else != nil {
+				state, _ = w.walkStmt(s.Else, state)
+			}
+			return state, false
+		}
+
+		bodyState, bodyTerminated := w.walkBlock(s.Body.List, state.clone())
+		elseState, elseTerminated := state.clone(), false
+		if s.Else != nil {
+			elseState, elseTerminated = w.walkStmt(s.Else, state.clone())
+		}
+		switch {
+		case bodyTerminated && elseTerminated:
+			return state, true
+		case bodyTerminated:
+			return elseState, false
+		case elseTerminated:
+			return bodyState, false
+		}
+		// 分岐内で獲得された可能性がある処理は獲得済みとみなす
+		bodyState.held = mergeNames(bodyState.held, elseState.held)
+		return bodyState, false
+	case *ast.BlockStmt:
+		return w.walkBlock(s.List, state)
+	case *ast.RangeStmt:
+		w.walkBlock(s.Body.List, state.clone())
+		return state, false
+	case *ast.ForStmt:
+		w.walkBlock(s.Body.List, state.clone())
+		return state, false
+	case *ast.DeferStmt:
+		for _, name := range w.compensatesIn(s) {
+			state.deferred = append(state.deferred, name)
+		}
+		return state, false
+	case *ast.AssignStmt, *ast.ExprStmt, *ast.DeclStmt:
+		for _, name := range w.compensatesIn(s) {
+			state.ran = append(state.ran, name)
+			state.held = removeName(state.held, w.acquireOf(name))
+		}
+		state.pending = append(state.pending, w.acquiresIn(s)...)
+		return state, false
+	}
+	return state, false
+}
+
+// recordExit は失敗時の出口での補償処理の実行状況を記録する
+func (w *compensationWalker) recordExit(ret *ast.ReturnStmt, state compensationState) {
+	a := w.analyzer
+	ran := mergeNames(state.ran, state.deferred)
+	held := append([]string(nil), state.held...)
+	for _, name := range state.deferred {
+		held = removeName(held, w.acquireOf(name))
+	}
+	if len(held) == 0 && len(ran) == 0 {
+		return
+	}
+
+	var missing []string
+	for _, acquire := range held {
+		missing = append(missing, w.compensateOf(acquire))
+	}
+	w.exits = append(w.exits, CompensationExit{
+		NodeID:  a.stmtNodes[ret],
+		Line:    a.fileSet.Position(ret.Pos()).Line,
+		Label:   a.stmtToString(ret),
+		Ran:     ran,
+		Missing: missing,
+	})
+}
+
+// acquiresIn は文に含まれる獲得処理の呼び出しを返す（無名関数の中は対象外）
+func (w *compensationWalker) acquiresIn(node ast.Node) []string {
+	var names []string
+	w.inspectCalls(node, func(method string) {
+		for _, pair := range w.analyzer.config.CompensationPairs {
+			if pair.Acquire == method {
+				names = append(names, method)
+			}
+		}
+	})
+	return names
+}
+
+// compensatesIn は文に含まれる補償処理の呼び出しを返す
+// defer文の場合は無名関数の中も対象にする
+func (w *compensationWalker) compensatesIn(node ast.Node) []string {
+	var names []string
+	collect := func(method string) {
+		for _, pair := range w.analyzer.config.CompensationPairs {
+			if pair.Compensate == method {
+				names = append(names, method)
+			}
+		}
+	}
+	if deferStmt, ok := node.(*ast.DeferStmt); ok {
+		if funcLit, ok := deferStmt.Call.Fun.(*ast.FuncLit); ok {
+			w.inspectCalls(funcLit.Body, collect)
+			return names
+		}
+	}
+	w.inspectCalls(node, collect)
+	return names
+}
+
+// inspectCalls は呼び出されるメソッド名（セレクタの末尾）ごとに fn を呼び出す
+func (w *compensationWalker) inspectCalls(node ast.Node, fn func(method string)) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			name := w.analyzer.extractCallName(e)
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				name = name[i+1:]
+			}
+			if name != "" {
+				fn(name)
+			}
+		}
+		return true
+	})
+}
+
+func (w *compensationWalker) acquireOf(compensate string) string {
+	for _, pair := range w.analyzer.config.CompensationPairs {
+		if pair.Compensate == compensate {
+			return pair.Acquire
+		}
+	}
+	return ""
+}
+
+func (w *compensationWalker) compensateOf(acquire string) string {
+	for _, pair := range w.analyzer.config.CompensationPairs {
+		if pair.Acquire == acquire {
+			return pair.Compensate
+		}
+	}
+	return ""
+}
+
+func removeName(names []string, target string) []string {
+	var result []string
+	for _, name := range names {
+		if name != target {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// mergeNames は重複を除いて2つの名前の並びを結合する
+func mergeNames(a, b []string) []string {
+	var result []string
+	for _, name := range append(append([]string(nil), a...), b...) {
+		if !containsString(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ノードのカバレッジ
+const (
+	coverageCovered   = "covered"   // テストで実行された
+	coverageUncovered = "uncovered" // テストで実行されていない
+)
+
+// coverBlock go test -coverprofile の1ブロック
+type coverBlock struct {
+	startLine int
+	endLine   int
+	numStmts  int
+	count     int
+}
+
+// analyzeCoverage は coverprofile を読み込み、ノードごとの実行有無と関数ごとのカバレッジ率を記録する
+func (a *Analyzer) analyzeCoverage() {
+	if a.config.CoverProfile == "" {
+		return
+	}
+	profile, err := a.loadCoverProfile(a.config.CoverProfile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		if a.config.Verbose {
+			fmt.Printf("警告: カバレッジを読み込めません (%v)\n", err)
+		}
+		return
+	}
+
+	for _, funcInfo := range a.functions {
+		blocks := profile[funcInfo.FileName]
+		if len(blocks) == 0 {
+			continue
+		}
+
+		// 関数の範囲内のブロックからカバレッジ率（実行された文の割合）を求める
+		total, covered := 0, 0
+		for _, block := range blocks {
+			if block.startLine < funcInfo.StartLine || block.endLine > funcInfo.EndLine {
+				continue
+			}
+			total += block.numStmts
+			if block.count > 0 {
+				covered += block.numStmts
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		percent := float64(covered) * 100 / float64(total)
+		funcInfo.Coverage = &percent
+
+		// ソースの行範囲が重なるブロックのいずれかが実行されていれば、ノードは実行されたとみなす
+		for _, node := range funcInfo.Nodes {
+			if node.StartLine == 0 {
+				continue
+			}
+			for _, block := range blocks {
+				if block.endLine < node.StartLine || block.startLine > node.EndLine {
+					continue
+				}
+				if block.count > 0 {
+					node.Coverage = coverageCovered
+					break
+				}
+				node.Coverage = coverageUncovered
+			}
+		}
+	}
+}
+
+// loadCoverProfile は coverprofile を読み込み、ファイル名（モジュールルートからの相対パス）-> ブロック を返す
+// 複数のテストで同じブロックが出力されている場合は実行回数を合算する
+func (a *Analyzer) loadCoverProfile(fileName string) (map[string][]coverBlock, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	type blockKey struct {
+		fileName string
+		position string
+	}
+	index := make(map[blockKey]int)
+	profile := make(map[string][]coverBlock)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+
+		// 例: github.com/owner/repo/application/service/pricing.go:98.93,100.2 1 1
+		colon := strings.LastIndex(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("coverprofile の形式が不正です: %s", line)
+		}
+		var block coverBlock
+		var startCol, endCol int
+		if _, err := fmt.Sscanf(line[colon+1:], "%d.%d,%d.%d %d %d",
+			&block.startLine, &startCol, &block.endLine, &endCol, &block.numStmts, &block.count); err != nil {
+			return nil, fmt.Errorf("coverprofile の形式が不正です: %s", line)
+		}
+
+		importPath := line[:colon]
+		if !a.isModulePackage(path.Dir(importPath)) {
+			continue
+		}
+		name := filepath.Join(a.dirOf(path.Dir(importPath)), path.Base(importPath))
+
+		key := blockKey{fileName: name, position: strings.Fields(line[colon+1:])[0]}
+		if i, ok := index[key]; ok {
+			profile[name][i].count += block.count
+			continue
+		}
+		index[key] = len(profile[name])
+		profile[name] = append(profile[name], block)
+	}
+	return profile, scanner.Err()
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// paramFieldWrite 関数が引数のフィールドに書き込むこと（pricing.CouponDiscount = ... など）
+type paramFieldWrite struct {
+	Param int    // 引数の位置（レシーバーは -1）
+	Path  string // 引数からのフィールドのパス（.CouponDiscount など）
+}
+
+// DataFlowEntry 関数内の変数・フィールドごとの、書き込むノードと読み取るノード
+type DataFlowEntry struct {
+	Name    string   `json:"name"` // pricing.CouponDiscount など
+	Defines []string `json:"defines,omitempty"`
+	Uses    []string `json:"uses,omitempty"`
+}
+
+// analyzeDataFlow はドキュメント化対象の関数のノードごとに、書き込む変数・フィールドと読み取る変数・フィールドを記録する
+// 呼び出し先の関数が引数のフィールドに書き込む場合は、呼び出したノードでの書き込みとして扱う
+func (a *Analyzer) analyzeDataFlow() {
+	summaries := make(map[*types.Func][]paramFieldWrite)
+	for decl, funcInfo := range a.functionsByDecl {
+		if decl.Body == nil {
+			continue
+		}
+		receiver := a.receiverVar(decl)
+		defines := make(map[string]map[string]bool) // ノードID -> 書き込む変数・フィールド
+		uses := make(map[string]map[string]bool)    // ノードID -> 読み取る変数・フィールド
+		record := func(target map[string]map[string]bool, expr ast.Node, path string) {
+			nodeID := nodeAtLine(funcInfo, a.fileSet.Position(expr.Pos()).Line)
+			if nodeID == "" {
+				return
+			}
+			if target[nodeID] == nil {
+				target[nodeID] = make(map[string]bool)
+			}
+			target[nodeID][path] = true
+		}
+
+		written := a.definedExprs(decl.Body)
+		ast.Inspect(decl.Body, func(n ast.Node) bool {
+			switch e := n.(type) {
+			case *ast.CallExpr:
+				// 呼び出し先が引数のフィールドに書き込むなら、渡した変数のフィールドへの書き込みとする
+				for _, write := range a.callParamFieldWrites(e, summaries, make(map[*types.Func]bool)) {
+					if path, _ := a.dataPath(callArg(a.info, e, write.Param)); path != "" {
+						record(defines, e, path+write.Path)
+					}
+				}
+				return true
+			case *ast.Ident, *ast.SelectorExpr:
+				path, root := a.dataPath(e.(ast.Expr))
+				if path == "" {
+					// メソッドや関数の参照は、レシーバーなど内側の式だけを対象にする
+					return true
+				}
+				if a.isDataExpr(e.(ast.Expr), root, receiver) {
+					access, ok := written[e.(ast.Expr)]
+					if ok {
+						record(defines, e, path)
+					}
+					if !ok || access == token.ADD_ASSIGN || access == token.INC {
+						// += や ++ は書き込みと同時に読み取る
+						record(uses, e, path)
+					}
+				}
+				// フィールドのパスの途中の変数は読み取りとして数えない
+				return false
+			}
+			return true
+		})
+
+		for nodeID, nodeInfo := range funcInfo.Nodes {
+			nodeInfo.Defines = sortedSet(defines[nodeID])
+			nodeInfo.Uses = sortedSet(uses[nodeID])
+		}
+	}
+}
+
+// definedExprs は代入・インクリメント・変数宣言・range で書き込まれる式を、書き込みの種類とともに返す
+// 複合代入（+= など）とインクリメント・デクリメントは読み取りも伴うため、それぞれ token.ADD_ASSIGN と token.INC にまとめる
+func (a *Analyzer) definedExprs(body *ast.BlockStmt) map[ast.Expr]token.Token {
+	written := make(map[ast.Expr]token.Token)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.AssignStmt:
+			tok := token.ASSIGN
+			if s.Tok != token.ASSIGN && s.Tok != token.DEFINE {
+				tok = token.ADD_ASSIGN
+			}
+			for _, lhs := range s.Lhs {
+				written[lhs] = tok
+			}
+		case *ast.IncDecStmt:
+			written[s.X] = token.INC
+		case *ast.RangeStmt:
+			if s.Key != nil {
+				written[s.Key] = token.ASSIGN
+			}
+			if s.Value != nil {
+				written[s.Value] = token.ASSIGN
+			}
+		case *ast.ValueSpec:
+			for _, name := range s.Names {
+				written[name] = token.ASSIGN
+			}
+		}
+		return true
+	})
+	return written
+}
+
+// dataPath は変数またはそのフィールドをたどる式（pricing.CouponDiscount など）を文字列にし、起点の変数を返す
+// ポインタの参照外し・アドレス演算子・括弧は取り除く。変数から始まらない式は空文字列を返す
+func (a *Analyzer) dataPath(expr ast.Expr) (string, *types.Var) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		obj := a.info.Uses[e]
+		if obj == nil {
+			obj = a.info.Defs[e]
+		}
+		v, ok := obj.(*types.Var)
+		if !ok || v.IsField() || e.Name == "_" {
+			return "", nil
+		}
+		return e.Name, v
+	case *ast.SelectorExpr:
+		selection := a.info.Selections[e]
+		if selection == nil || selection.Kind() != types.FieldVal {
+			return "", nil
+		}
+		path, root := a.dataPath(e.X)
+		if path == "" {
+			return "", nil
+		}
+		return path + "." + e.Sel.Name, root
+	case *ast.StarExpr:
+		return a.dataPath(e.X)
+	case *ast.ParenExpr:
+		return a.dataPath(e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return a.dataPath(e.X)
+		}
+	}
+	return "", nil
+}
+
+// isDataExpr はデータフローに表示する変数・フィールドかどうかを判定する
+// インターフェースと関数の値（依存するサービス、error、context.Context など）と、レシーバーそのものは処理するデータではないため除く
+func (a *Analyzer) isDataExpr(expr ast.Expr, root *types.Var, receiver *types.Var) bool {
+	if root == nil {
+		return false
+	}
+	if ident, ok := expr.(*ast.Ident); ok && root == receiver && ident.Name == receiver.Name() {
+		return false
+	}
+	if t := a.info.TypeOf(expr); t != nil {
+		switch t.Underlying().(type) {
+		case *types.Interface, *types.Signature:
+			return false
+		}
+	}
+	return true
+}
+
+// receiverVar はメソッドのレシーバーの変数を返す（関数なら nil）
+func (a *Analyzer) receiverVar(decl *ast.FuncDecl) *types.Var {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 || len(decl.Recv.List[0].Names) == 0 {
+		return nil
+	}
+	v, _ := a.info.Defs[decl.Recv.List[0].Names[0]].(*types.Var)
+	return v
+}
+
+// callParamFieldWrites は呼び出し先の関数が引数のフィールドに書き込む箇所を返す
+// インターフェースのメソッド呼び出しは、モジュール内の実装すべての書き込みを合わせる
+func (a *Analyzer) callParamFieldWrites(call *ast.CallExpr, summaries map[*types.Func][]paramFieldWrite, visiting map[*types.Func]bool) []paramFieldWrite {
+	var writes []paramFieldWrite
+	for _, fn := range a.resolveCallTargets(call) {
+		writes = append(writes, a.paramFieldWrites(fn, summaries, visiting)...)
+	}
+	return writes
+}
+
+// callArg は呼び出しの引数の式を返す（-1 はメソッドのレシーバー）
+// インターフェースを通した呼び出しのレシーバーは実装が分からないため nil を返す
+func callArg(info *types.Info, call *ast.CallExpr, param int) ast.Expr {
+	if param >= 0 {
+		if param < len(call.Args) {
+			return call.Args[param]
+		}
+		return nil
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	if selection := info.Selections[sel]; selection == nil || selection.Kind() != types.MethodVal || types.IsInterface(selection.Recv()) {
+		return nil
+	}
+	return sel.X
+}
+
+// paramFieldWrites は関数が引数のフィールドに書き込む箇所を、呼び出し先の関数をたどって返す（関数ごとにメモ化）
+func (a *Analyzer) paramFieldWrites(fn *types.Func, summaries map[*types.Func][]paramFieldWrite, visiting map[*types.Func]bool) []paramFieldWrite {
+	if fn == nil {
+		return nil
+	}
+	fn = fn.Origin()
+	if writes, ok := summaries[fn]; ok {
+		return writes
+	}
+	decl := a.funcDecls[fn]
+	if decl == nil || decl.Body == nil || visiting[fn] {
+		return nil
+	}
+	visiting[fn] = true
+
+	params := make(map[*types.Var]int)
+	signature := fn.Type().(*types.Signature)
+	if signature.Recv() != nil {
+		params[signature.Recv()] = -1
+	}
+	for i := 0; i < signature.Params().Len(); i++ {
+		params[signature.Params().At(i)] = i
+	}
+
+	var writes []paramFieldWrite
+	seen := make(map[paramFieldWrite]bool)
+	add := func(expr ast.Expr, suffix string) {
+		path, root := a.dataPath(expr)
+		if root == nil {
+			return
+		}
+		i, ok := params[root]
+		if !ok || path == root.Name() && suffix == "" {
+			// 引数の変数自体への代入は呼び出し元に影響しない
+			return
+		}
+		write := paramFieldWrite{Param: i, Path: path[len(root.Name()):] + suffix}
+		if !seen[write] {
+			seen[write] = true
+			writes = append(writes, write)
+		}
+	}
+	for expr := range a.definedExprs(decl.Body) {
+		add(expr, "")
+	}
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		for _, write := range a.callParamFieldWrites(call, summaries, visiting) {
+			if arg := callArg(a.info, call, write.Param); arg != nil {
+				add(arg, write.Path)
+			}
+		}
+		return true
+	})
+	sort.Slice(writes, func(i, j int) bool {
+		if writes[i].Param != writes[j].Param {
+			return writes[i].Param < writes[j].Param
+		}
+		return writes[i].Path < writes[j].Path
+	})
+
+	delete(visiting, fn)
+	summaries[fn] = writes
+	return writes
+}
+
+// buildDataFlow はノードごとの書き込み・読み取りを、変数・フィールドごとの一覧にまとめる（関数ページの表で使う）
+func buildDataFlow(funcInfo *FunctionInfo) []DataFlowEntry {
+	entries := make(map[string]*DataFlowEntry)
+	entry := func(name string) *DataFlowEntry {
+		if entries[name] == nil {
+			entries[name] = &DataFlowEntry{Name: name}
+		}
+		return entries[name]
+	}
+	if funcInfo.flowchart == nil {
+		return nil
+	}
+	// フローチャートのノードの順に並べる
+	for _, node := range funcInfo.flowchart.nodes {
+		nodeInfo := funcInfo.Nodes[nodeIDOf(node)]
+		if nodeInfo == nil {
+			continue
+		}
+		for _, name := range nodeInfo.Defines {
+			e := entry(name)
+			e.Defines = append(e.Defines, nodeIDOf(node))
+		}
+		for _, name := range nodeInfo.Uses {
+			e := entry(name)
+			e.Uses = append(e.Uses, nodeIDOf(node))
+		}
+	}
+
+	result := make([]DataFlowEntry, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, *e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// sortedSet は集合の要素を並べ替えて返す
+func sortedSet(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(set))
+	for value := range set {
+		result = append(result, value)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// Diagram 関数単位のフローチャート以外の観点で生成する図
+type Diagram struct {
+	ID          string         `json:"id"`       // URLハッシュで使う識別子（例: state:entity.OrderStatus）
+	Category    string         `json:"category"` // サイドバーでの分類（例: 状態遷移図）
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	MermaidCode string         `json:"mermaidCode"`
+	Tables      []DiagramTable `json:"tables"` // 図の下に表示する補足の表
+}
+
+// DiagramTable 図の補足として表示する表
+type DiagramTable struct {
+	Title    string          `json:"title"`
+	Columns  []string        `json:"columns"`
+	Rows     [][]DiagramCell `json:"rows"`
+	Sortable bool            `json:"sortable,omitempty"` // 列の見出しで並べ替えられるようにする
+}
+
+// DiagramCell 表のセル。関数やソースの位置を持つ場合はリンクとして表示する
+type DiagramCell struct {
+	Text     string `json:"text"`
+	Function string `json:"function,omitempty"` // ドキュメント化された関数（ID）
+	Node     string `json:"node,omitempty"`     // Function のフローチャート上のノードID
+	FileName string `json:"fileName,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	URL      string `json:"url,omitempty"`     // リポジトリホスト上のURL（生成時に設定）
+	Warning  bool   `json:"warning,omitempty"` // しきい値の超過などを強調表示する
+}
+
+// Diagrams は解析で生成した図を ID 順に返す
+func (a *Analyzer) Diagrams() []*Diagram {
+	sort.SliceStable(a.diagrams, func(i, j int) bool {
+		return a.diagrams[i].ID < a.diagrams[j].ID
+	})
+	return a.diagrams
+}
+
+// functionCell は関数宣言を表のセルにする（ドキュメント化対象ならリンクにする）
+func (a *Analyzer) functionCell(decl *ast.FuncDecl) DiagramCell {
+	cell := DiagramCell{Text: decl.Name.Name}
+	if obj, ok := a.info.Defs[decl.Name].(*types.Func); ok {
+		cell.Text = a.displayName(obj)
+	}
+	if funcInfo, ok := a.functionsByDecl[decl]; ok {
+		cell.Function = funcInfo.ID
+	}
+	return cell
+}
+
+// positionCell はソース上の位置を表のセルにする
+func (a *Analyzer) positionCell(pos token.Pos) DiagramCell {
+	position := a.fileSet.Position(pos)
+	return DiagramCell{
+		Text:     fmt.Sprintf("%s:%d", position.Filename, position.Line),
+		FileName: position.Filename,
+		Line:     position.Line,
+	}
+}
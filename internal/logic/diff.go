@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ノードの差分の種類
+const (
+	nodeAdded    = "追加"
+	nodeRemoved  = "削除"
+	nodeModified = "変更"
+)
+
+// removedNodePrefix は差分の図で削除されたノード（変更前のフローチャートのノード）に付けるIDの接頭辞
+const removedNodePrefix = "D_"
+
+// nodeChange 変更前後で対応が取れなかったノード
+type nodeChange struct {
+	kind   string
+	before string // 変更前のノード定義（追加では空）
+	after  string // 変更後のノード定義（削除では空）
+}
+
+// functionDiff 関数のフローチャートの差分
+type functionDiff struct {
+	before  *FunctionInfo // 変更前（追加された関数では nil）
+	after   *FunctionInfo // 変更後（削除された関数では nil）
+	changes []nodeChange
+	matched map[string]string // 変更前のノードID -> 変更後のノードID（変更されたノードも含む）
+	edges   int               // 両端が対応するノードなのに変更前後で異なるエッジの数
+}
+
+// runDiff は diff サブコマンドを実行する
+// 2つのリビジョンを git worktree に取り出してそれぞれ解析し、変更された関数ごとの差分の図を生成する
+//
+//	go run ./internal/logic diff --from main --to HEAD
+func runDiff(config *Config, args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	from := flags.String("from", "", "変更前のリビジョン（必須）")
+	to := flags.String("to", "", "変更後のリビジョン（省略すると作業ツリー）")
+	outputDir := flags.String("out", "docs-diff", "出力先ディレクトリ")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" {
+		return fmt.Errorf("--from を指定してください")
+	}
+
+	before, _, err := analyzeRevision(config, *from)
+	if err != nil {
+		return fmt.Errorf("%s の解析に失敗しました: %w", *from, err)
+	}
+	after, analyzer, err := analyzeRevision(config, *to)
+	if err != nil {
+		return fmt.Errorf("%s の解析に失敗しました: %w", revisionName(*to), err)
+	}
+
+	diffs := diffFunctions(before, after)
+	fmt.Printf("差分: %s..%s で %d個の関数が変更されています\n", *from, revisionName(*to), len(diffs))
+
+	diffConfig := *config
+	diffConfig.OutputDir = *outputDir
+	generator := NewHTMLGenerator(&diffConfig)
+	if *to != "" {
+		// ソースへのリンクは変更後のリビジョンを指す
+		if commit, err := gitOutput("rev-parse", *to+"^{commit}"); err == nil && generator.gitRoot != "" {
+			generator.commit = commit
+		}
+	}
+	diagrams := append(analyzer.diffDiagrams(diffs, *from, revisionName(*to)), analyzer.Diagrams()...)
+	if err := generator.GenerateDocumentation(after, diagrams, nil); err != nil {
+		return err
+	}
+
+	fmt.Printf("差分のドキュメントを生成しました: %s\n", *outputDir)
+	return nil
+}
+
+// analyzeRevision はリビジョンを一時的な git worktree に取り出して解析する（空なら作業ツリーをそのまま解析する）
+func analyzeRevision(config *Config, revision string) (map[string]*FunctionInfo, *Analyzer, error) {
+	if revision == "" {
+		analyzer := NewAnalyzer(config)
+		functions, err := analyzer.AnalyzeAllTargetFiles()
+		return functions, analyzer, err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, err
+	}
+	gitRoot, err := findGitRoot(cwd)
+	if err != nil {
+		return nil, nil, err
+	}
+	// ワークスペースのルートがリポジトリのサブディレクトリにある場合は worktree 内の同じ位置で解析する
+	moduleDir, err := filepath.Rel(gitRoot, cwd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	worktree, err := os.MkdirTemp("", "logic-worktree-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(worktree)
+	if _, err := gitOutput("worktree", "add", "--detach", worktree, revision); err != nil {
+		return nil, nil, err
+	}
+	defer gitOutput("worktree", "remove", "--force", worktree)
+
+	// 解析は作業ディレクトリからの相対パスで行うため、worktree に移動して解析する
+	if err := os.Chdir(filepath.Join(worktree, moduleDir)); err != nil {
+		return nil, nil, err
+	}
+	defer os.Chdir(cwd)
+
+	analyzer := NewAnalyzer(config)
+	functions, err := analyzer.AnalyzeAllTargetFiles()
+	return functions, analyzer, err
+}
+
+// gitOutput は git コマンドを実行し、標準出力を返す
+func gitOutput(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func revisionName(revision string) string {
+	if revision == "" {
+		return "作業ツリー"
+	}
+	return revision
+}
+
+// diffFunctions は変更前後の関数を ID で対応付け、フローチャートが変わった関数の差分を返す
+func diffFunctions(before, after map[string]*FunctionInfo) []*functionDiff {
+	names := make(map[string]bool)
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	var diffs []*functionDiff
+	for _, name := range sortedNames {
+		diff := diffFunction(before[name], after[name])
+		if len(diff.changes) > 0 || diff.edges > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
+
+// diffFunction はノード定義（形状と表示文言）の最長共通部分列で変更前後のノードを対応付ける
+// 対応が取れなかったノードは、同じ区間にある変更前後のものを順に「変更」として組にし、残りを追加・削除とする
+func diffFunction(before, after *FunctionInfo) *functionDiff {
+	diff := &functionDiff{before: before, after: after, matched: make(map[string]string)}
+	var oldNodes, newNodes, oldEdges, newEdges []string
+	if before != nil && before.flowchart != nil {
+		oldNodes, oldEdges = before.flowchart.nodes, before.flowchart.edges
+	}
+	if after != nil && after.flowchart != nil {
+		newNodes, newEdges = after.flowchart.nodes, after.flowchart.edges
+	}
+
+	key := func(node string) string { return node[len(nodeIDOf(node)):] }
+	lcs := make([][]int, len(oldNodes)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newNodes)+1)
+	}
+	for i := len(oldNodes) - 1; i >= 0; i-- {
+		for j := len(newNodes) - 1; j >= 0; j-- {
+			if key(oldNodes[i]) == key(newNodes[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// 共通部分の間に残ったノードを変更・削除・追加に振り分ける
+	var removed, added []string
+	flush := func() {
+		for len(removed) > 0 && len(added) > 0 {
+			diff.matched[nodeIDOf(removed[0])] = nodeIDOf(added[0])
+			diff.changes = append(diff.changes, nodeChange{kind: nodeModified, before: removed[0], after: added[0]})
+			removed, added = removed[1:], added[1:]
+		}
+		for _, node := range removed {
+			diff.changes = append(diff.changes, nodeChange{kind: nodeRemoved, before: node})
+		}
+		for _, node := range added {
+			diff.changes = append(diff.changes, nodeChange{kind: nodeAdded, after: node})
+		}
+		removed, added = nil, nil
+	}
+	i, j := 0, 0
+	for i < len(oldNodes) || j < len(newNodes) {
+		switch {
+		case i < len(oldNodes) && j < len(newNodes) && key(oldNodes[i]) == key(newNodes[j]):
+			flush()
+			diff.matched[nodeIDOf(oldNodes[i])] = nodeIDOf(newNodes[j])
+			i++
+			j++
+		case j == len(newNodes) || (i < len(oldNodes) && lcs[i+1][j] >= lcs[i][j+1]):
+			removed = append(removed, oldNodes[i])
+			i++
+		default:
+			added = append(added, newNodes[j])
+			j++
+		}
+	}
+	flush()
+
+	// 両端が対応するエッジを比べ、つなぎ方が変わったものを数える
+	oldSet := make(map[string]bool)
+	for _, edge := range oldEdges {
+		from, label, to := splitEdge(edge)
+		if diff.matched[from] != "" && diff.matched[to] != "" {
+			oldSet[diff.matched[from]+"|"+label+"|"+diff.matched[to]] = true
+		}
+	}
+	newSet := make(map[string]bool)
+	for _, edge := range newEdges {
+		from, label, to := splitEdge(edge)
+		newSet[from+"|"+label+"|"+to] = true
+	}
+	matchedNew := make(map[string]bool)
+	for _, newID := range diff.matched {
+		matchedNew[newID] = true
+	}
+	for edge := range oldSet {
+		if !newSet[edge] {
+			diff.edges++
+		}
+	}
+	for edge := range newSet {
+		parts := strings.Split(edge, "|")
+		if matchedNew[parts[0]] && matchedNew[parts[2]] && !oldSet[edge] {
+			diff.edges++
+		}
+	}
+	return diff
+}
+
+// splitEdge は "A --> |label| B" 形式のエッジを分解する
+func splitEdge(edge string) (from, label, to string) {
+	fields := strings.Fields(edge)
+	if len(fields) == 4 {
+		label = strings.Trim(fields[2], "|")
+	}
+	return fields[0], label, fields[len(fields)-1]
+}
+
+// diffDiagrams は差分の概要と、変更された関数ごとの差分の図を作る
+func (a *Analyzer) diffDiagrams(diffs []*functionDiff, from, to string) []*Diagram {
+	category := fmt.Sprintf("差分 %s..%s", from, to)
+	summary := DiagramTable{
+		Title:    "変更された関数",
+		Columns:  []string{"関数", "状態", "追加", "削除", "変更", "つなぎ方の変更", "位置"},
+		Sortable: true,
+	}
+	var diagrams []*Diagram
+	for _, diff := range diffs {
+		info := diff.after
+		state := "変更"
+		if diff.before == nil {
+			state = "追加"
+		} else if diff.after == nil {
+			info = diff.before
+			state = "削除"
+		}
+		counts := make(map[string]int)
+		for _, change := range diff.changes {
+			counts[change.kind]++
+		}
+
+		id := "diff:" + info.ID
+		nameCell := DiagramCell{Text: info.FullName}
+		if diff.after != nil {
+			nameCell.Function = info.ID
+		}
+		positionCell := DiagramCell{
+			Text:     fmt.Sprintf("%s:%d", info.FileName, info.StartLine),
+			FileName: info.FileName,
+			Line:     info.StartLine,
+		}
+		if diff.after == nil {
+			// 削除された関数の位置は変更後のリビジョンに存在しないのでリンクにしない
+			positionCell.FileName = ""
+			positionCell.Line = 0
+		}
+		summary.Rows = append(summary.Rows, []DiagramCell{
+			nameCell,
+			{Text: state},
+			{Text: fmt.Sprint(counts[nodeAdded])},
+			{Text: fmt.Sprint(counts[nodeRemoved])},
+			{Text: fmt.Sprint(counts[nodeModified])},
+			{Text: fmt.Sprint(diff.edges)},
+			positionCell,
+		})
+
+		changes := DiagramTable{
+			Title:   "変更されたノード",
+			Columns: []string{"種類", "変更前", "変更後", "ノード"},
+		}
+		for _, change := range diff.changes {
+			nodeCell := DiagramCell{}
+			if change.after != "" {
+				nodeID := nodeIDOf(change.after)
+				nodeCell = DiagramCell{Text: nodeID, Function: info.ID, Node: nodeID}
+			}
+			changes.Rows = append(changes.Rows, []DiagramCell{
+				{Text: change.kind, Warning: change.kind == nodeRemoved},
+				{Text: nodeLabelText(change.before)},
+				{Text: nodeLabelText(change.after)},
+				nodeCell,
+			})
+		}
+
+		diagrams = append(diagrams, &Diagram{
+			ID:       id,
+			Category: category,
+			Title:    info.FullName,
+			Description: fmt.Sprintf("%s から %s への変更です（%s）。追加したノードは緑、変更したノードは青、削除したノードは赤の点線で表示します。",
+				from, to, state),
+			MermaidCode: a.diffMermaidCode(diff),
+			Tables:      []DiagramTable{changes},
+		})
+	}
+
+	overview := &Diagram{
+		ID:          "diff",
+		Category:    category,
+		Title:       "変更の概要",
+		Description: fmt.Sprintf("%s から %s までにフローチャートが変わった関数の一覧です。", from, to),
+		Tables:      []DiagramTable{summary},
+	}
+	if len(diffs) == 0 {
+		overview.Description = fmt.Sprintf("%s から %s までにフローチャートが変わった関数はありません。", from, to)
+		overview.Tables = nil
+	}
+	return append([]*Diagram{overview}, diagrams...)
+}
+
+// diffMermaidCode は変更後のフローチャートに削除されたノードを重ね、差分の種類ごとに色を付けたMermaidコードを返す
+func (a *Analyzer) diffMermaidCode(diff *functionDiff) string {
+	var buf bytes.Buffer
+	buf.WriteString("flowchart TD\n")
+
+	var added, removed, modified []string
+	if diff.after != nil {
+		for _, node := range diff.after.flowchart.nodes {
+			buf.WriteString(fmt.Sprintf("    %s\n", node))
+		}
+		for _, edge := range diff.after.flowchart.edges {
+			buf.WriteString(fmt.Sprintf("    %s\n", edge))
+		}
+	}
+	for _, change := range diff.changes {
+		switch change.kind {
+		case nodeAdded:
+			added = append(added, nodeIDOf(change.after))
+		case nodeModified:
+			modified = append(modified, nodeIDOf(change.after))
+		case nodeRemoved:
+			removed = append(removed, removedNodePrefix+nodeIDOf(change.before))
+			buf.WriteString(fmt.Sprintf("    %s%s\n", removedNodePrefix, change.before))
+		}
+	}
+
+	// 削除されたノードにつながっていたエッジを点線で書き出す
+	if diff.before != nil {
+		for _, edge := range diff.before.flowchart.edges {
+			from, label, to := splitEdge(edge)
+			_, fromMatched := diff.matched[from]
+			_, toMatched := diff.matched[to]
+			if fromMatched && toMatched {
+				continue
+			}
+			if diff.after == nil || !fromMatched {
+				from = removedNodePrefix + from
+			} else {
+				from = diff.matched[from]
+			}
+			if diff.after == nil || !toMatched {
+				to = removedNodePrefix + to
+			} else {
+				to = diff.matched[to]
+			}
+			if label != "" {
+				buf.WriteString(fmt.Sprintf("    %s -.-> |%s| %s\n", from, label, to))
+			} else {
+				buf.WriteString(fmt.Sprintf("    %s -.-> %s\n", from, to))
+			}
+		}
+	}
+
+	buf.WriteString("    classDef diffAdded fill:#d1e7dd,stroke:#198754,color:#0f5132\n")
+	buf.WriteString("    classDef diffRemoved fill:#f8d7da,stroke:#dc3545,color:#842029,stroke-dasharray:5 3\n")
+	buf.WriteString("    classDef diffModified fill:#cfe2ff,stroke:#0d6efd,color:#084298\n")
+	if len(added) > 0 {
+		buf.WriteString(fmt.Sprintf("    class %s diffAdded\n", strings.Join(added, ",")))
+	}
+	if len(removed) > 0 {
+		buf.WriteString(fmt.Sprintf("    class %s diffRemoved\n", strings.Join(removed, ",")))
+	}
+	if len(modified) > 0 {
+		buf.WriteString(fmt.Sprintf("    class %s diffModified\n", strings.Join(modified, ",")))
+	}
+	return buf.String()
+}
+
+// nodeLabelText はノード定義から表示文言を取り出す
+func nodeLabelText(node string) string {
+	start := strings.Index(node, "\"")
+	end := strings.LastIndex(node, "\"")
+	if start < 0 || end <= start {
+		return ""
+	}
+	label := node[start+1 : end]
+	label = strings.Trim(label, "`*")
+	return strings.NewReplacer(
+		"\\n", " ",
+		"#quot;", "\"",
+		"\\{", "{",
+		"\\}", "}",
+		"\\<", "<",
+		"\\>", ">",
+	).Replace(label)
+}
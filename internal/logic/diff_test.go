@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffFunction(t *testing.T) {
+	chart := func(nodes []string, edges ...string) *FunctionInfo {
+		return &FunctionInfo{flowchart: &flowchart{nodes: nodes, edges: edges}}
+	}
+	base := []string{`N1(["開始"])`, `N2["a := 1"]`, `N3{{"a > 0"}}`, `N4(["return a"])`}
+	baseEdges := []string{"N1 --> N2", "N2 --> N3", "N3 --> |Yes| N4"}
+
+	tests := []struct {
+		name    string
+		before  *FunctionInfo
+		after   *FunctionInfo
+		changes []nodeChange
+		matched map[string]string
+		edges   int
+	}{
+		{
+			name:    "変更なし",
+			before:  chart(base, baseEdges...),
+			after:   chart(base, baseEdges...),
+			matched: map[string]string{"N1": "N1", "N2": "N2", "N3": "N3", "N4": "N4"},
+		},
+		{
+			name:   "文言の変更",
+			before: chart(base, baseEdges...),
+			after:  chart([]string{`N1(["開始"])`, `N2["a := 2"]`, `N3{{"a > 0"}}`, `N4(["return a"])`}, baseEdges...),
+			changes: []nodeChange{
+				{kind: nodeModified, before: `N2["a := 1"]`, after: `N2["a := 2"]`},
+			},
+			matched: map[string]string{"N1": "N1", "N2": "N2", "N3": "N3", "N4": "N4"},
+		},
+		{
+			name:   "ノードの追加（後ろのノードIDがずれても対応付ける）",
+			before: chart(base, baseEdges...),
+			after: chart([]string{`N1(["開始"])`, `N2["log()"]`, `N3["a := 1"]`, `N4{{"a > 0"}}`, `N5(["return a"])`},
+				"N1 --> N2", "N2 --> N3", "N3 --> N4", "N4 --> |Yes| N5"),
+			changes: []nodeChange{
+				{kind: nodeAdded, after: `N2["log()"]`},
+			},
+			matched: map[string]string{"N1": "N1", "N2": "N3", "N3": "N4", "N4": "N5"},
+			edges:   1, // N1 --> N3 が N1 --> N2 --> N3 になった
+		},
+		{
+			name:   "ノードの削除",
+			before: chart(base, baseEdges...),
+			after:  chart([]string{`N1(["開始"])`, `N2{{"a > 0"}}`, `N3(["return a"])`}, "N1 --> N2", "N2 --> |Yes| N3"),
+			changes: []nodeChange{
+				{kind: nodeRemoved, before: `N2["a := 1"]`},
+			},
+			matched: map[string]string{"N1": "N1", "N3": "N2", "N4": "N3"},
+			edges:   1, // N1 --> N3 が新たにつながった
+		},
+		{
+			name:    "エッジのラベルの変更",
+			before:  chart(base, baseEdges...),
+			after:   chart(base, "N1 --> N2", "N2 --> N3", "N3 --> |No| N4"),
+			matched: map[string]string{"N1": "N1", "N2": "N2", "N3": "N3", "N4": "N4"},
+			edges:   2,
+		},
+		{
+			name:   "追加された関数",
+			before: nil,
+			after:  chart(base[:2], "N1 --> N2"),
+			changes: []nodeChange{
+				{kind: nodeAdded, after: `N1(["開始"])`},
+				{kind: nodeAdded, after: `N2["a := 1"]`},
+			},
+			matched: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := diffFunction(tt.before, tt.after)
+			if !reflect.DeepEqual(diff.changes, tt.changes) {
+				t.Errorf("changes = %+v, want %+v", diff.changes, tt.changes)
+			}
+			if !reflect.DeepEqual(diff.matched, tt.matched) {
+				t.Errorf("matched = %v, want %v", diff.matched, tt.matched)
+			}
+			if diff.edges != tt.edges {
+				t.Errorf("edges = %d, want %d", diff.edges, tt.edges)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/shibuya-mizuho/logic-mermaid-pages/internal/logictrace"
+)
+
+// directivePrefix はフローチャートの表示を指定するコメントの接頭辞
+//
+//	//logic:label 在庫を予約する   ノードの表示をこの文言に置き換える
+//	//logic:hide                 文をフローチャートに表示しない
+//	//logic:group 決済            この文からブロックの終わり（または次の //logic:group）までをグループにまとめる
+//	//logic:group                グループを終了する
+const directivePrefix = "//logic:"
+
+// stmtAnnotation 文の直前に書かれたコメントとディレクティブ
+type stmtAnnotation struct {
+	comments []string // ディレクティブ以外のコメント
+	label    string   // //logic:label の文言
+	hide     bool     // //logic:hide
+	group    *string  // //logic:group のグループ名（指定がなければ nil）
+}
+
+// annotationOf は文の直前のコメントとディレクティブを返す
+func (a *Analyzer) annotationOf(stmt ast.Stmt) stmtAnnotation {
+	annotation := stmtAnnotation{comments: a.getComments(stmt)}
+	// 実行経路を記録するための呼び出しはロジックではないので表示しない
+	if a.isTraceCall(stmt) {
+		annotation.hide = true
+		position := a.fileSet.Position(stmt.Pos())
+		a.traceCalls[logictrace.Position{File: position.Filename, Line: position.Line}] = true
+	}
+	for _, directive := range a.getDirectives(stmt) {
+		name, arg, _ := strings.Cut(directive, " ")
+		arg = strings.TrimSpace(arg)
+		switch name {
+		case "label":
+			annotation.label = arg
+		case "hide":
+			annotation.hide = true
+		case "group":
+			annotation.group = &arg
+		default:
+			if a.config.Verbose {
+				fmt.Printf("警告: 不明なディレクティブ %s%s (%s)\n", directivePrefix, name, a.fileSet.Position(stmt.Pos()))
+			}
+		}
+	}
+	return annotation
+}
+
+// text はノードに表示する文言を返す（//logic:label があればそれだけを表示する）
+func (n stmtAnnotation) text(code string) string {
+	if n.label != "" {
+		return n.label
+	}
+	if len(n.comments) > 0 {
+		return strings.Join(n.comments, "\\n") + "\\n" + code
+	}
+	return code
+}
+
+// comment はコメントのみ表示でノードに表示する文言を返す（注釈がなければ空）
+func (n stmtAnnotation) comment() string {
+	if n.label != "" {
+		return n.label
+	}
+	return strings.Join(n.comments, "\\n")
+}
+
+// enterGroup は //logic:group があれば、以降の文を生成するノードのグループを切り替える
+func (a *Analyzer) enterGroup(annotation stmtAnnotation) {
+	if annotation.group != nil {
+		a.currentGroup = *annotation.group
+	}
+}
+
+// annotateNode は文から生成したノードにコメントのみ表示用の文言を記録する
+func (a *Analyzer) annotateNode(nodeID string, annotation stmtAnnotation) {
+	if info, ok := a.nodeInfos[nodeID]; ok {
+		info.Comment = annotation.comment()
+	}
+}
+
+// writeNodeGroups は //logic:group でまとめたノードをサブグラフとして書き出す
+func (a *Analyzer) writeNodeGroups(buf *bytes.Buffer, nodeIDs []string) {
+	var groups []string
+	members := make(map[string][]string)
+	for _, nodeID := range nodeIDs {
+		info, ok := a.nodeInfos[nodeID]
+		if !ok || info.Group == "" {
+			continue
+		}
+		if _, seen := members[info.Group]; !seen {
+			groups = append(groups, info.Group)
+		}
+		members[info.Group] = append(members[info.Group], nodeID)
+	}
+	for i, group := range groups {
+		buf.WriteString(fmt.Sprintf("    subgraph G%d[\"%s\"]\n", i+1, a.escapeString(group)))
+		for _, nodeID := range members[group] {
+			buf.WriteString(fmt.Sprintf("        %s\n", nodeID))
+		}
+		buf.WriteString("    end\n")
+	}
+}
+
+// commentOnlyGraph はコメントのみ表示のノードとエッジを返す
+// 注釈のあるノードは注釈だけを表示し、注釈のない処理ノードは取り除いて前後をつなぐ
+// 条件・ループ・return など流れの分かれ目になるノードは注釈がなければそのまま残す
+func (a *Analyzer) commentOnlyGraph(nodes, edges []string) ([]string, []string) {
+	type edge struct{ from, label, to string }
+	var graph []edge
+	for _, e := range edges {
+		fields := strings.Fields(e)
+		ge := edge{from: fields[0], to: fields[len(fields)-1]}
+		if len(fields) == 4 {
+			ge.label = strings.Trim(fields[2], "|")
+		}
+		graph = append(graph, ge)
+	}
+	hasOutgoing := func(nodeID string) bool {
+		for _, e := range graph {
+			if e.from == nodeID {
+				return true
+			}
+		}
+		return false
+	}
+
+	var result []string
+	for _, node := range nodes {
+		nodeID := nodeIDOf(node)
+		info := a.nodeInfos[nodeID]
+		if info != nil && info.Comment != "" {
+			// 表示文言だけを注釈に置き換える（形状はそのまま）
+			start := strings.Index(node, "\"")
+			end := strings.LastIndex(node, "\"")
+			result = append(result, node[:start+1]+a.escapeString(info.Comment)+node[end:])
+			continue
+		}
+		if !strings.HasPrefix(node[len(nodeID):], "[\"") || !hasOutgoing(nodeID) {
+			result = append(result, node)
+			continue
+		}
+
+		// 取り除くノードへの入力を出力先へつなぎ替える（分岐のラベルは入力側を優先する）
+		var incoming, outgoing, rest []edge
+		for _, e := range graph {
+			switch {
+			case e.to == nodeID:
+				incoming = append(incoming, e)
+			case e.from == nodeID:
+				outgoing = append(outgoing, e)
+			default:
+				rest = append(rest, e)
+			}
+		}
+		for _, in := range incoming {
+			for _, out := range outgoing {
+				if in.from == out.to {
+					continue
+				}
+				label := in.label
+				if label == "" {
+					label = out.label
+				}
+				bypass := edge{from: in.from, label: label, to: out.to}
+				duplicate := false
+				for _, e := range rest {
+					if e == bypass {
+						duplicate = true
+						break
+					}
+				}
+				if !duplicate {
+					rest = append(rest, bypass)
+				}
+			}
+		}
+		graph = rest
+	}
+
+	var resultEdges []string
+	for _, e := range graph {
+		if e.label != "" {
+			resultEdges = append(resultEdges, fmt.Sprintf("%s --> |%s| %s", e.from, e.label, e.to))
+		} else {
+			resultEdges = append(resultEdges, fmt.Sprintf("%s --> %s", e.from, e.to))
+		}
+	}
+	return result, resultEdges
+}
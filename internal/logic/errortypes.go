@@ -0,0 +1,406 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// ReturnedError 関数が返しうるエラー型と、その発生条件
+type ReturnedError struct {
+	Type      string   `json:"type"`      // entity.OrderStateError など。型を特定できない場合は空
+	Source    string   `json:"source"`    // 型を特定できない場合のエラーの出どころ（errors.New、未解析の呼び出しなど）
+	Condition string   `json:"condition"` // 発生条件（if文の条件を && で連結）
+	Via       []string `json:"via"`       // 経由した呼び出し（直接返す場合は空）
+	Wrapped   bool     `json:"wrapped"`   // fmt.Errorf の %w でラップされている
+	FileName  string   `json:"fileName"`
+	Line      int      `json:"line"`
+}
+
+func (e ReturnedError) key() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s:%d", e.Type, e.Source, e.Condition, strings.Join(e.Via, ">"), e.FileName, e.Line)
+}
+
+// analyzeReturnedErrors はドキュメント化対象の各関数が返しうるエラーを、呼び出しグラフをたどって求める
+func (a *Analyzer) analyzeReturnedErrors() {
+	for decl, funcInfo := range a.functionsByDecl {
+		obj, ok := a.info.Defs[decl.Name].(*types.Func)
+		if !ok {
+			continue
+		}
+		funcInfo.ReturnedErrors = a.returnedErrorsOf(obj)
+	}
+}
+
+// returnedErrorsOf は関数が返しうるエラーを求める（呼び出し先を再帰的にたどり、結果はメモ化する）
+func (a *Analyzer) returnedErrorsOf(fn *types.Func) []ReturnedError {
+	if errs, ok := a.returnedErrors[fn]; ok {
+		return errs
+	}
+	decl, ok := a.funcDecls[fn]
+	if !ok || decl.Body == nil || !returnsErrorType(fn) {
+		return nil
+	}
+	if depth, ok := a.errorStack[fn]; ok {
+		// 探索中の関数への再帰呼び出しは空集合として扱い、循環の起点を記録する
+		if a.errorCycleStart < 0 || depth < a.errorCycleStart {
+			a.errorCycleStart = depth
+		}
+		return nil
+	}
+
+	depth := len(a.errorStack)
+	a.errorStack[fn] = depth
+	outerCycleStart := a.errorCycleStart
+	a.errorCycleStart = -1
+
+	collector := &errorCollector{analyzer: a, decl: decl, seen: make(map[string]bool)}
+	collector.walkStmts(decl.Body.List, nil)
+
+	sort.SliceStable(collector.errors, func(i, j int) bool {
+		return collector.errors[i].Type != "" && collector.errors[j].Type == ""
+	})
+	delete(a.errorStack, fn)
+
+	// 探索中の呼び出し元に循環していれば結果は未確定なので、メモ化は循環の起点の関数に任せる
+	if a.errorCycleStart < 0 || a.errorCycleStart >= depth {
+		a.returnedErrors[fn] = collector.errors
+		a.errorCycleStart = outerCycleStart
+	} else if outerCycleStart >= 0 && outerCycleStart < a.errorCycleStart {
+		a.errorCycleStart = outerCycleStart
+	}
+	return collector.errors
+}
+
+// returnsErrorType は関数の最後の戻り値が error 型かどうかを返す
+func returnsErrorType(fn *types.Func) bool {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Results().Len() == 0 {
+		return false
+	}
+	last := sig.Results().At(sig.Results().Len() - 1).Type()
+	return types.Identical(last, types.Universe.Lookup("error").Type())
+}
+
+// errorCollector は1つの関数の本体をたどり、return文で返されるエラーを集める
+type errorCollector struct {
+	analyzer *Analyzer
+	decl     *ast.FuncDecl
+	errors   []ReturnedError
+	seen     map[string]bool
+}
+
+func (c *errorCollector) add(e ReturnedError) {
+	if !c.seen[e.key()] {
+		c.seen[e.key()] = true
+		c.errors = append(c.errors, e)
+	}
+}
+
+// walkStmts は文をたどり、return文ごとにその時点で成り立つ条件とともにエラーの出どころを調べる
+func (c *errorCollector) walkStmts(stmts []ast.Stmt, conds []string) {
+	for _, stmt := range stmts {
+		c.walkStmt(stmt, conds)
+	}
+}
+
+func (c *errorCollector) walkStmt(stmt ast.Stmt, conds []string) {
+	a := c.analyzer
+	switch s := stmt.(type) {
+	case *ast.ReturnStmt:
+		if len(s.Results) == 0 {
+			return
+		}
+		last := s.Results[len(s.Results)-1]
+		if isNilIdent(last) {
+			return
+		}
+		for _, e := range c.sourcesOf(last, s.Pos(), 0) {
+			e.Condition = joinConditions(conds, e.Condition)
+			c.add(e)
+		}
+	case *ast.IfStmt:
+		// err != nil の判定は条件として自明なので含めない
+		cond := ""
+		if !a.isErrorCheck(s.Cond) {
+			cond = a.exprToString(s.Cond)
+		}
+		c.walkStmts(s.Body.List, appendCondition(conds, cond))
+		if s.Else != nil {
+			elseCond := ""
+			if cond != "" {
+				elseCond = "!(" + cond + ")"
+			}
+			c.walkStmt(s.Else, appendCondition(conds, elseCond))
+		}
+	case *ast.BlockStmt:
+		c.walkStmts(s.List, conds)
+	case *ast.RangeStmt:
+		c.walkStmts(s.Body.List, conds)
+	case *ast.ForStmt:
+		c.walkStmts(s.Body.List, conds)
+	case *ast.SwitchStmt:
+		tag := a.exprToString(s.Tag)
+		var cases []string
+		for _, clause := range s.Body.List {
+			if cc := clause.(*ast.CaseClause); len(cc.List) > 0 {
+				cases = append(cases, caseCondition(a, tag, cc.List))
+			}
+		}
+		for _, clause := range s.Body.List {
+			cc := clause.(*ast.CaseClause)
+			cond := caseCondition(a, tag, cc.List)
+			if len(cc.List) == 0 {
+				cond = defaultCondition(cases)
+			}
+			c.walkStmts(cc.Body, appendCondition(conds, cond))
+		}
+	case *ast.TypeSwitchStmt:
+		for _, clause := range s.Body.List {
+			c.walkStmts(clause.(*ast.CaseClause).Body, conds)
+		}
+	case *ast.LabeledStmt:
+		c.walkStmt(s.Stmt, conds)
+	}
+}
+
+// caseCondition は switch の case 節を条件式の文字列にする（default 節は空文字列）
+func caseCondition(a *Analyzer, tag string, list []ast.Expr) string {
+	if len(list) == 0 {
+		return ""
+	}
+	var values []string
+	for _, expr := range list {
+		if tag == "" {
+			values = append(values, a.exprToString(expr))
+		} else {
+			values = append(values, tag+" == "+a.exprToString(expr))
+		}
+	}
+	return strings.Join(values, " || ")
+}
+
+// defaultCondition は default 節の条件として、ほかの case 節のどれにも当てはまらないことを表す条件式を返す
+func defaultCondition(cases []string) string {
+	if len(cases) == 0 {
+		return ""
+	}
+	return "!(" + strings.Join(cases, " || ") + ")"
+}
+
+func appendCondition(conds []string, cond string) []string {
+	if cond == "" {
+		return conds
+	}
+	result := make([]string, 0, len(conds)+1)
+	result = append(result, conds...)
+	return append(result, cond)
+}
+
+// joinConditions は条件を && で連結する。|| を含む条件は、優先順位が変わらないよう括弧で囲む
+func joinConditions(conds []string, inner string) string {
+	all := appendCondition(conds, inner)
+	if len(all) == 1 {
+		return all[0]
+	}
+	parts := make([]string, len(all))
+	for i, cond := range all {
+		if hasTopLevelOr(cond) {
+			cond = "(" + cond + ")"
+		}
+		parts[i] = cond
+	}
+	return strings.Join(parts, " && ")
+}
+
+// hasTopLevelOr は条件式の括弧の外に || があるかどうかを返す（文字列リテラルの中は除く）
+func hasTopLevelOr(cond string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(cond); i++ {
+		ch := cond[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote != '`' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'' || ch == '`':
+			quote = ch
+		case ch == '(' || ch == '[' || ch == '{':
+			depth++
+		case ch == ')' || ch == ']' || ch == '}':
+			depth--
+		case ch == '|' && depth == 0 && i+1 < len(cond) && cond[i+1] == '|':
+			return true
+		}
+	}
+	return false
+}
+
+// calleeCondition は呼び出し先の関数内の条件に、その関数名を付ける
+// 条件の変数名は呼び出し先の引数名なので、呼び出し元の条件と区別できるようにする
+func calleeCondition(callee string, cond string) string {
+	if cond == "" {
+		return ""
+	}
+	return callee + " 内で (" + cond + ")"
+}
+
+// sourcesOf はエラー式の出どころを調べる
+// pos はその式が評価される位置で、変数の場合はそれより前の最後の代入を出どころとみなす
+func (c *errorCollector) sourcesOf(expr ast.Expr, pos token.Pos, depth int) []ReturnedError {
+	a := c.analyzer
+	line := a.fileSet.Position(expr.Pos()).Line
+	fileName := a.fileSet.Position(expr.Pos()).Filename
+	opaque := func(source string) []ReturnedError {
+		return []ReturnedError{{Source: source, FileName: fileName, Line: line}}
+	}
+	if depth > 8 {
+		return opaque(a.exprToString(expr))
+	}
+
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return c.sourcesOf(e.X, pos, depth+1)
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return c.sourcesOf(e.X, pos, depth+1)
+		}
+	case *ast.CompositeLit:
+		if t := a.info.TypeOf(e); t != nil {
+			return []ReturnedError{{Type: typeName(t), FileName: fileName, Line: line}}
+		}
+		return opaque(a.exprToString(e.Type))
+	case *ast.Ident:
+		if isNilIdent(e) {
+			return nil
+		}
+		if rhs := c.lastAssignment(e, pos); rhs != nil {
+			return c.sourcesOf(rhs, rhs.Pos(), depth+1)
+		}
+		return opaque(e.Name)
+	case *ast.CallExpr:
+		return c.callSources(e, depth)
+	}
+	return opaque(a.exprToString(expr))
+}
+
+// callSources は関数呼び出しが返すエラーの出どころを調べる
+func (c *errorCollector) callSources(call *ast.CallExpr, depth int) []ReturnedError {
+	a := c.analyzer
+	callName := a.extractCallName(call)
+	line := a.fileSet.Position(call.Pos()).Line
+	fileName := a.fileSet.Position(call.Pos()).Filename
+
+	switch callName {
+	case "errors.New":
+		return []ReturnedError{{Source: "errors.New", FileName: fileName, Line: line}}
+	case "fmt.Errorf":
+		if len(call.Args) == 0 || !wrapsError(a, call.Args[0]) {
+			return []ReturnedError{{Source: "fmt.Errorf", FileName: fileName, Line: line}}
+		}
+		// %w でラップされたエラーは errors.As で取り出せるので、元のエラー型を引き継ぐ
+		var result []ReturnedError
+		for _, arg := range call.Args[1:] {
+			if t := a.info.TypeOf(arg); t != nil && !types.Implements(t, errorInterface()) {
+				continue
+			}
+			for _, e := range c.sourcesOf(arg, call.Pos(), depth+1) {
+				e.Wrapped = true
+				result = append(result, e)
+			}
+		}
+		return result
+	}
+
+	targets := a.resolveCallTargets(call)
+	var result []ReturnedError
+	resolved := false
+	for _, target := range targets {
+		if _, ok := a.funcDecls[target]; !ok {
+			continue
+		}
+		resolved = true
+		for _, e := range a.returnedErrorsOf(target) {
+			e.Via = append([]string{a.displayName(target)}, e.Via...)
+			e.Condition = calleeCondition(a.displayName(target), e.Condition)
+			result = append(result, e)
+		}
+	}
+	if !resolved {
+		return []ReturnedError{{Source: callName, FileName: fileName, Line: line}}
+	}
+	return result
+}
+
+// lastAssignment は識別子が指す変数に、pos より前で最後に代入された式を返す
+func (c *errorCollector) lastAssignment(ident *ast.Ident, pos token.Pos) ast.Expr {
+	a := c.analyzer
+	obj := a.info.Uses[ident]
+	if obj == nil {
+		return nil
+	}
+
+	var result ast.Expr
+	var resultPos token.Pos
+	ast.Inspect(c.decl.Body, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok || assign.Pos() >= pos || assign.Pos() < resultPos {
+			return true
+		}
+		for i, lhs := range assign.Lhs {
+			lhsIdent, ok := lhs.(*ast.Ident)
+			if !ok || (a.info.Defs[lhsIdent] != obj && a.info.Uses[lhsIdent] != obj) {
+				continue
+			}
+			if len(assign.Rhs) == len(assign.Lhs) {
+				result = assign.Rhs[i]
+			} else {
+				// 多値を返す関数呼び出し
+				result = assign.Rhs[0]
+			}
+			resultPos = assign.Pos()
+		}
+		return true
+	})
+	return result
+}
+
+// wrapsError は fmt.Errorf のフォーマット文字列に %w が含まれるかどうかを返す
+func wrapsError(a *Analyzer, format ast.Expr) bool {
+	if tv, ok := a.info.Types[format]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+		return strings.Contains(constant.StringVal(tv.Value), "%w")
+	}
+	if lit, ok := format.(*ast.BasicLit); ok {
+		return strings.Contains(lit.Value, "%w")
+	}
+	return false
+}
+
+func errorInterface() *types.Interface {
+	return types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+}
+
+// displayName は関数オブジェクトを Receiver.Method 形式で表示する
+func (a *Analyzer) displayName(fn *types.Func) string {
+	if info := a.functionOf(fn); info != nil {
+		return info.FullName
+	}
+	sig, _ := fn.Type().(*types.Signature)
+	if sig != nil && sig.Recv() != nil {
+		return typeName(sig.Recv().Type()) + "." + fn.Name()
+	}
+	if fn.Pkg() != nil {
+		return fn.Pkg().Name() + "." + fn.Name()
+	}
+	return fn.Name()
+}
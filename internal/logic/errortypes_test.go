@@ -0,0 +1,89 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"testing"
+)
+
+func TestJoinConditions(t *testing.T) {
+	tests := []struct {
+		name  string
+		conds []string
+		inner string
+		want  string
+	}{
+		{name: "条件なし", want: ""},
+		{name: "1つだけなら括弧を付けない", conds: []string{"a || b"}, want: "a || b"},
+		{name: "&& で連結", conds: []string{"a", "b"}, inner: "c", want: "a && b && c"},
+		{name: "|| を含む条件は括弧で囲む", conds: []string{"a == x || a == y"}, inner: "b <= 0", want: "(a == x || a == y) && b <= 0"},
+		{name: "括弧の中の || はそのまま", conds: []string{"!(a || b)"}, inner: "f(c || d)", want: "!(a || b) && f(c || d)"},
+		{name: "文字列リテラルの中の || は無視", conds: []string{`s == "a || b"`}, inner: "c", want: `s == "a || b" && c`},
+		{name: "呼び出し先の条件", conds: []string{"a"}, inner: calleeCondition("svc.Check", "x || y"), want: "a && svc.Check 内で (x || y)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinConditions(tt.conds, tt.inner); got != tt.want {
+				t.Errorf("joinConditions(%q, %q) = %q, want %q", tt.conds, tt.inner, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaseCondition(t *testing.T) {
+	a := NewAnalyzer(&Config{})
+	parse := func(exprs ...string) []ast.Expr {
+		var list []ast.Expr
+		for _, s := range exprs {
+			expr, err := parser.ParseExpr(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			list = append(list, expr)
+		}
+		return list
+	}
+
+	tests := []struct {
+		name string
+		tag  string
+		list []ast.Expr
+		want string
+	}{
+		{name: "default 節は条件なし", tag: "method", want: ""},
+		{name: "値が1つ", tag: "method", list: parse("entity.PaymentMethodPoints"), want: "method == entity.PaymentMethodPoints"},
+		{name: "値が複数", tag: "method", list: parse("entity.PaymentMethodPoints", "entity.PaymentMethodCombined"),
+			want: "method == entity.PaymentMethodPoints || method == entity.PaymentMethodCombined"},
+		{name: "タグなしの switch", list: parse("n < 0", "n > 10"), want: "n < 0 || n > 10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := caseCondition(a, tt.tag, tt.list); got != tt.want {
+				t.Errorf("caseCondition() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// default 節はほかの case 節のどれにも当てはまらないこと
+	cases := []string{
+		caseCondition(a, "method", parse("entity.PaymentMethodPoints", "entity.PaymentMethodCombined")),
+		caseCondition(a, "method", parse("entity.PaymentMethodCreditCard")),
+	}
+	wantDefault := "!(method == entity.PaymentMethodPoints || method == entity.PaymentMethodCombined || method == entity.PaymentMethodCreditCard)"
+	if got := defaultCondition(cases); got != wantDefault {
+		t.Errorf("defaultCondition() = %q, want %q", got, wantDefault)
+	}
+	if got := defaultCondition(nil); got != "" {
+		t.Errorf("defaultCondition(nil) = %q, want empty", got)
+	}
+	if got := joinConditions([]string{"a"}, caseCondition(a, "method", nil)); got != "a" {
+		t.Errorf("joinConditions() = %q, want %q", got, "a")
+	}
+
+	// 複数の値の case 節を他の条件と連結しても、条件の意味が変わらないこと
+	cond := caseCondition(a, "method", parse("entity.PaymentMethodPoints", "entity.PaymentMethodCombined"))
+	want := "(method == entity.PaymentMethodPoints || method == entity.PaymentMethodCombined) && points <= 0"
+	if got := joinConditions([]string{cond}, "points <= 0"); got != want {
+		t.Errorf("joinConditions() = %q, want %q", got, want)
+	}
+}
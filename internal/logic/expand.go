@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// expandWriter は呼び出し先のフローチャートをサブグラフとして埋め込んだMermaidコードを組み立てる
+type expandWriter struct {
+	analyzer             *Analyzer
+	body                 bytes.Buffer
+	clicks               []string
+	errorChecks          []string
+	errorPaths           []string
+	missingCompensations []string
+	expanded             bool
+}
+
+// generateExpandedMermaidCode は呼び出しノードごとに、呼び出し先の関数のフローチャートを
+// サブグラフとして展開したMermaidコードを生成する
+// 展開は Config.ExpandDepth の深さまでで、展開できる呼び出しがなければ空文字列を返す
+func (a *Analyzer) generateExpandedMermaidCode(funcInfo *FunctionInfo) string {
+	if a.config.ExpandDepth <= 0 || funcInfo.flowchart == nil {
+		return ""
+	}
+
+	w := &expandWriter{analyzer: a}
+	w.writeFunction(funcInfo, "", 0, []string{funcInfo.ID}, "    ")
+	if !w.expanded {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("flowchart TD\n")
+	buf.Write(w.body.Bytes())
+	for _, click := range w.clicks {
+		buf.WriteString(click)
+	}
+	writeNodeClasses(&buf, w.errorChecks, w.errorPaths, w.missingCompensations)
+	return buf.String()
+}
+
+// writeFunction は関数のノードとエッジを prefix を付けたIDで書き出し、呼び出し先を再帰的に展開する
+// stack は展開中の関数の並びで、再帰呼び出しの展開を防ぐ
+func (w *expandWriter) writeFunction(funcInfo *FunctionInfo, prefix string, depth int, stack []string, indent string) {
+	chart := funcInfo.flowchart
+	for _, node := range chart.nodes {
+		nodeID := nodeIDOf(node)
+		w.body.WriteString(indent + prefix + node + "\n")
+		w.classify(prefix+nodeID, funcInfo.Nodes[nodeID])
+	}
+	for _, edge := range chart.edges {
+		w.body.WriteString(indent + prefixEdge(edge, prefix) + "\n")
+	}
+
+	present := make(map[string]bool)
+	for _, node := range chart.nodes {
+		present[nodeIDOf(node)] = true
+	}
+	var callNodeIDs []string
+	for nodeID := range chart.callNodes {
+		if present[nodeID] {
+			callNodeIDs = append(callNodeIDs, nodeID)
+		}
+	}
+	sortNodeIDs(callNodeIDs)
+
+	for _, nodeID := range callNodeIDs {
+		callName := chart.callNodes[nodeID]
+		if click := w.clickOf(funcInfo, prefix, nodeID, callName); click != "" {
+			w.clicks = append(w.clicks, click)
+		}
+
+		callee := w.calleeOf(funcInfo, callName)
+		if callee == nil || callee.flowchart == nil || depth >= w.analyzer.config.ExpandDepth {
+			continue
+		}
+		if containsString(stack, callee.ID) {
+			// 再帰呼び出しは展開せず、その旨を示すノードだけを置く
+			recursionID := prefix + nodeID + "_R"
+			w.body.WriteString(fmt.Sprintf("%s%s[[\"再帰呼び出し: %s\"]]\n", indent, recursionID, w.analyzer.escapeString(callee.FullName)))
+			w.body.WriteString(fmt.Sprintf("%s%s%s -.-> %s\n", indent, prefix, nodeID, recursionID))
+			continue
+		}
+
+		childPrefix := prefix + nodeID + "_"
+		w.body.WriteString(fmt.Sprintf("%ssubgraph %sSG[\"%s\"]\n", indent, childPrefix, w.analyzer.escapeString(callee.FullName)))
+		w.writeFunction(callee, childPrefix, depth+1, append(stack, callee.ID), indent+"    ")
+		w.body.WriteString(indent + "end\n")
+		w.body.WriteString(fmt.Sprintf("%s%s%s -.->|展開| %sN1\n", indent, prefix, nodeID, childPrefix))
+		w.expanded = true
+	}
+}
+
+// calleeOf は呼び出し名に対応するドキュメント化対象の関数を返す
+// インターフェース経由で実装が複数ある場合は展開しない
+func (w *expandWriter) calleeOf(funcInfo *FunctionInfo, callName string) *FunctionInfo {
+	targets := funcInfo.CallTargets[callName]
+	if len(targets) != 1 {
+		return nil
+	}
+	return w.analyzer.functions[targets[0]]
+}
+
+// clickOf は呼び出しノードのクリックで開く関数を、そのノードを含む関数自身の呼び出し先から決める
+// 展開した呼び出し先の呼び出し名は表示中の関数の callTargets にないため、解決済みの関数IDへ直接移動する
+func (w *expandWriter) clickOf(funcInfo *FunctionInfo, prefix, nodeID, callName string) string {
+	if targets := funcInfo.CallTargets[callName]; len(targets) > 0 {
+		return fmt.Sprintf("    click %s%s href \"#%s\"\n", prefix, nodeID, targets[0])
+	}
+	if prefix == "" {
+		// 表示中の関数の呼び出しは、通常のフローチャートと同じく呼び出し名から探す
+		return fmt.Sprintf("    click %s \"javascript:navigateToFunction('%s')\"\n", nodeID, callName)
+	}
+	// 展開した関数内の未解決の呼び出しは、呼び出し名から探すと別の関数を開きうるためクリックできなくする
+	return ""
+}
+
+func (w *expandWriter) classify(nodeID string, info *NodeInfo) {
+	if info == nil {
+		return
+	}
+	if info.ErrorCheck {
+		w.errorChecks = append(w.errorChecks, nodeID)
+	} else if info.ErrorPath {
+		w.errorPaths = append(w.errorPaths, nodeID)
+	}
+	if info.MissingCompensation {
+		w.missingCompensations = append(w.missingCompensations, nodeID)
+	}
+}
+
+// prefixEdge はエッジの両端のノードIDに prefix を付ける
+func prefixEdge(edge, prefix string) string {
+	fields := strings.Fields(edge)
+	if len(fields) < 3 {
+		return edge
+	}
+	fields[0] = prefix + fields[0]
+	fields[len(fields)-1] = prefix + fields[len(fields)-1]
+	return strings.Join(fields, " ")
+}
+
+// sortNodeIDs は N2, N10 のようなノードIDを番号順に並べる
+func sortNodeIDs(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		if len(ids[i]) != len(ids[j]) {
+			return len(ids[i]) < len(ids[j])
+		}
+		return ids[i] < ids[j]
+	})
+}
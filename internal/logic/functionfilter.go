@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"path"
+	"strings"
+)
+
+// FunctionFilter ドキュメント化する関数の条件
+// //logic:entrypoint を付けた関数は条件にかかわらず対象、//logic:ignore を付けた関数は常に対象外
+type FunctionFilter struct {
+	ExportedOnly     bool     // 公開されている関数・メソッドのみ対象にする
+	MinStatements    int      // 文の数がこれより少ない関数を除外する（0で除外しない）
+	Receivers        []string // 対象にするメソッドのレシーバー型（path.Match のパターン、空なら全て）
+	ExcludeReceivers []string // 除外するメソッドのレシーバー型（path.Match のパターン）
+}
+
+// functionDirectives 関数のドキュメントコメントに書かれた //logic: ディレクティブ
+type functionDirectives struct {
+	ignore     bool     // //logic:ignore
+	entrypoint bool     // //logic:entrypoint
+	tags       []string // //logic:tag（空白区切りで複数指定できる）
+}
+
+// functionDirectivesOf は関数のドキュメントコメントからディレクティブを読み取る
+func (a *Analyzer) functionDirectivesOf(funcDecl *ast.FuncDecl) functionDirectives {
+	var directives functionDirectives
+	if funcDecl.Doc == nil {
+		return directives
+	}
+	for _, comment := range funcDecl.Doc.List {
+		if !strings.HasPrefix(comment.Text, directivePrefix) {
+			continue
+		}
+		name, arg, _ := strings.Cut(strings.TrimPrefix(comment.Text, directivePrefix), " ")
+		switch name {
+		case "ignore":
+			directives.ignore = true
+		case "entrypoint":
+			directives.entrypoint = true
+		case "tag":
+			for _, tag := range strings.Fields(arg) {
+				directives.tags = appendUnique(directives.tags, tag)
+			}
+		default:
+			if a.config.Verbose {
+				fmt.Printf("警告: 不明なディレクティブ %s%s (%s)\n", directivePrefix, name, a.fileSet.Position(comment.Pos()))
+			}
+		}
+	}
+	return directives
+}
+
+// selectFunctionsInFile はファイル内の関数宣言をドキュメント化するものと除外するものに分ける
+func (a *Analyzer) selectFunctionsInFile(file *ast.File) {
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		directives := a.functionDirectivesOf(funcDecl)
+		if a.shouldDocument(funcDecl, directives) {
+			a.selectedDecls[funcDecl] = directives
+		} else {
+			a.filteredDecls[funcDecl] = true
+		}
+	}
+}
+
+// isFilteredCall は呼び出し先がすべて、Functions の条件で除外した関数かどうかを返す
+// そのような呼び出しのノードはクリックできないようにする（呼び出し名で探すと別の関数を開きうるため）
+func (a *Analyzer) isFilteredCall(call *ast.CallExpr) bool {
+	targets := a.resolveCallTargets(call)
+	if len(targets) == 0 {
+		return false
+	}
+	for _, target := range targets {
+		if !a.filteredDecls[a.funcDecls[target]] {
+			return false
+		}
+	}
+	return true
+}
+
+// shouldDocument は関数をドキュメント化するかどうかを返す
+func (a *Analyzer) shouldDocument(funcDecl *ast.FuncDecl, directives functionDirectives) bool {
+	if directives.ignore {
+		return false
+	}
+	if directives.entrypoint {
+		return true
+	}
+
+	filter := a.config.Functions
+	if filter.ExportedOnly && !funcDecl.Name.IsExported() {
+		return false
+	}
+	if filter.MinStatements > 0 && countStatements(funcDecl.Body) < filter.MinStatements {
+		return false
+	}
+	if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
+		receiverType := a.extractReceiverType(funcDecl.Recv.List[0].Type)
+		if len(filter.Receivers) > 0 && !matchAny(filter.Receivers, receiverType) {
+			return false
+		}
+		if matchAny(filter.ExcludeReceivers, receiverType) {
+			return false
+		}
+	}
+	return true
+}
+
+// countStatements は関数本体に含まれる文の数を数える（ブロックは数えない）
+func countStatements(body *ast.BlockStmt) int {
+	if body == nil {
+		return 0
+	}
+	count := 0
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.BlockStmt, *ast.EmptyStmt:
+		case ast.Stmt:
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
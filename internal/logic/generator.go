@@ -8,6 +8,8 @@ import (
 	"html/template"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	textTemplate "text/template"
 	"time"
 )
@@ -19,6 +21,8 @@ type HTMLGenerator struct {
 	config        *Config
 	htmlTemplates *template.Template
 	jsTemplates   *textTemplate.Template
+	gitRoot       string
+	commit        string
 }
 
 // getGeneratedAt は現在時刻を日本語形式で返す
@@ -35,14 +39,65 @@ func NewHTMLGenerator(config *Config) *HTMLGenerator {
 	// JavaScript用テンプレート（HTMLエスケープなし）
 	jsTemplates := textTemplate.Must(textTemplate.ParseFS(templateFS, "templates/js/*.tmpl"))
 
-	return &HTMLGenerator{
+	generator := &HTMLGenerator{
 		config:        config,
 		htmlTemplates: htmlTemplates,
 		jsTemplates:   jsTemplates,
 	}
+	generator.resolveRepository()
+	return generator
 }
 
-func (g *HTMLGenerator) GenerateDocumentation(functions map[string]*FunctionInfo) error {
+// resolveRepository はソースリンク用にGitのルートとコミットSHAを解決する
+func (g *HTMLGenerator) resolveRepository() {
+	if g.config.RepositoryURL == "" {
+		return
+	}
+
+	gitRoot, err := findGitRoot(".")
+	if err != nil {
+		if g.config.Verbose {
+			fmt.Printf("警告: Gitリポジトリが見つからないためソースリンクを生成しません (%v)\n", err)
+		}
+		return
+	}
+	g.gitRoot = gitRoot
+
+	commit, err := readGitCommit(gitRoot)
+	if err != nil {
+		if g.config.Verbose {
+			fmt.Printf("警告: コミットSHAを取得できないため HEAD を使用します (%v)\n", err)
+		}
+		commit = "HEAD"
+	}
+	g.commit = commit
+}
+
+// sourceURL はファイルと行範囲からリポジトリホスト上のURLを組み立てる
+func (g *HTMLGenerator) sourceURL(fileName string, startLine, endLine int) string {
+	if g.gitRoot == "" || startLine == 0 {
+		return ""
+	}
+
+	absPath, err := filepath.Abs(fileName)
+	if err != nil {
+		return ""
+	}
+	relPath, err := filepath.Rel(g.gitRoot, absPath)
+	if err != nil {
+		return ""
+	}
+
+	replacer := strings.NewReplacer(
+		"{commit}", g.commit,
+		"{path}", filepath.ToSlash(relPath),
+		"{line}", strconv.Itoa(startLine),
+		"{endLine}", strconv.Itoa(endLine),
+	)
+	return replacer.Replace(g.config.RepositoryURL)
+}
+
+func (g *HTMLGenerator) GenerateDocumentation(functions map[string]*FunctionInfo, diagrams []*Diagram, traces []*ExecutionTrace) error {
 	// 出力ディレクトリ作成
 	if err := ensureDir(g.config.OutputDir); err != nil {
 		return err
@@ -61,6 +116,26 @@ func (g *HTMLGenerator) GenerateDocumentation(functions map[string]*FunctionInfo
 		return err
 	}
 
+	// 全文検索の索引生成
+	if err := g.generateSearchJS(functions); err != nil {
+		return err
+	}
+
+	// 型・フィールド・定数の逆引きの索引生成
+	if err := g.generateXrefJS(functions); err != nil {
+		return err
+	}
+
+	// JavaScript図データ生成
+	if err := g.generateDiagramsJS(diagrams); err != nil {
+		return err
+	}
+
+	// JavaScript実行経路データ生成
+	if err := g.generateTracesJS(traces); err != nil {
+		return err
+	}
+
 	// ナビゲーションJS生成
 	if err := g.generateNavigatorJS(); err != nil {
 		return err
@@ -76,18 +151,53 @@ func (g *HTMLGenerator) GenerateDocumentation(functions map[string]*FunctionInfo
 		return err
 	}
 
+	// 版の一覧を更新（最新の生成時のみ。版の生成時は runVersion が更新する）
+	if g.config.Version == "" {
+		if err := g.generateVersionsJS(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// generateVersionsJS は生成済みの版があれば、versions.json から画面用の versions.js を書き出す
+func (g *HTMLGenerator) generateVersionsJS() error {
+	manifest, err := loadVersionManifest(g.config.OutputDir)
+	if err != nil {
+		return err
+	}
+	if len(manifest.Versions) == 0 {
+		return nil
+	}
+	jsonData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeVersionsJS(g.config.OutputDir, jsonData)
+}
+
+// rootPath は index.html から OutputDir の直下（versions.js の置き場所）への相対パスを返す
+func (g *HTMLGenerator) rootPath() string {
+	if g.config.Version == "" {
+		return ""
+	}
+	return "../../"
+}
+
 func (g *HTMLGenerator) generateMainHTML(functions map[string]*FunctionInfo) error {
 	data := struct {
 		FunctionCount int
 		GeneratedAt   string
 		Version       string
+		DocVersion    string // 版の名前（最新なら空）
+		RootPath      string
 	}{
 		FunctionCount: len(functions),
 		GeneratedAt:   g.getGeneratedAt(),
 		Version:       time.Now().Format("200601021504"),
+		DocVersion:    g.config.Version,
+		RootPath:      g.rootPath(),
 	}
 
 	file, err := os.Create(filepath.Join(g.config.OutputDir, "index.html"))
@@ -103,15 +213,50 @@ func (g *HTMLGenerator) generateFunctionsJS(functions map[string]*FunctionInfo)
 	// 関数データをJSONに変換
 	functionsData := make(map[string]interface{})
 	for name, info := range functions {
+		nodesData := make(map[string]interface{})
+		for nodeID, node := range info.Nodes {
+			nodesData[nodeID] = map[string]interface{}{
+				"startLine": node.StartLine,
+				"endLine":   node.EndLine,
+				"url":       g.sourceURL(info.FileName, node.StartLine, node.EndLine),
+				"coverage":  node.Coverage,
+				"defines":   node.Defines,
+				"uses":      node.Uses,
+			}
+		}
+
 		functionsData[name] = map[string]interface{}{
-			"packageName":     info.PackageName,
-			"fileName":        info.FileName,
-			"functionName":    info.FunctionName,
-			"fullName":        info.FullName,
-			"receiverType":    info.ReceiverType,
-			"mermaidCode":     info.MermaidCode,
-			"calledFunctions": info.CalledFunctions,
-			"comments":        info.Comments,
+			"id":                   info.ID,
+			"module":               info.Module,
+			"packagePath":          info.PackagePath,
+			"packageName":          info.PackageName,
+			"fileName":             info.FileName,
+			"functionName":         info.FunctionName,
+			"fullName":             info.FullName,
+			"receiverType":         info.ReceiverType,
+			"receiverTypeParams":   info.ReceiverTypeParams,
+			"typeParams":           info.TypeParams,
+			"mermaidCode":          info.MermaidCode,
+			"happyPathMermaidCode": info.HappyPathMermaidCode,
+			"commentMermaidCode":   info.CommentMermaidCode,
+			"commentHappyPathCode": info.CommentHappyPathCode,
+			"expandedMermaidCode":  info.ExpandedMermaidCode,
+			"calledFunctions":      info.CalledFunctions,
+			"comments":             info.Comments,
+			"sourceCode":           info.SourceCode,
+			"startLine":            info.StartLine,
+			"endLine":              info.EndLine,
+			"sourceURL":            g.sourceURL(info.FileName, info.StartLine, info.EndLine),
+			"nodes":                nodesData,
+			"callTargets":          info.CallTargets,
+			"returnedErrors":       info.ReturnedErrors,
+			"compensations":        info.Compensations,
+			"usedTypes":            info.UsedTypes,
+			"entryPoint":           info.EntryPoint,
+			"tags":                 info.Tags,
+			"metrics":              info.Metrics,
+			"coverage":             info.Coverage,
+			"dataFlow":             buildDataFlow(info),
 		}
 	}
 
@@ -132,6 +277,98 @@ func (g *HTMLGenerator) generateFunctionsJS(functions map[string]*FunctionInfo)
 	return err
 }
 
+func (g *HTMLGenerator) generateDiagramsJS(diagrams []*Diagram) error {
+	// 表のセルのソース位置をリンクに変換
+	for _, diagram := range diagrams {
+		for _, table := range diagram.Tables {
+			for _, row := range table.Rows {
+				for i := range row {
+					if row[i].FileName != "" {
+						row[i].URL = g.sourceURL(row[i].FileName, row[i].Line, row[i].Line)
+					}
+				}
+			}
+		}
+	}
+	if diagrams == nil {
+		diagrams = []*Diagram{}
+	}
+
+	jsonData, err := json.MarshalIndent(diagrams, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	jsContent := fmt.Sprintf("const diagramsData = %s;", string(jsonData))
+
+	file, err := os.Create(filepath.Join(g.config.OutputDir, "assets", "diagrams.js"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(jsContent)
+	return err
+}
+
+func (g *HTMLGenerator) generateSearchJS(functions map[string]*FunctionInfo) error {
+	jsonData, err := json.Marshal(buildSearchIndex(functions))
+	if err != nil {
+		return err
+	}
+
+	jsContent := fmt.Sprintf("const searchIndex = %s;", string(jsonData))
+
+	file, err := os.Create(filepath.Join(g.config.OutputDir, "assets", "search.js"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(jsContent)
+	return err
+}
+
+func (g *HTMLGenerator) generateXrefJS(functions map[string]*FunctionInfo) error {
+	jsonData, err := json.Marshal(buildXrefIndex(functions))
+	if err != nil {
+		return err
+	}
+
+	jsContent := fmt.Sprintf("const xrefData = %s;", string(jsonData))
+
+	file, err := os.Create(filepath.Join(g.config.OutputDir, "assets", "xref.js"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(jsContent)
+	return err
+}
+
+func (g *HTMLGenerator) generateTracesJS(traces []*ExecutionTrace) error {
+	if traces == nil {
+		traces = []*ExecutionTrace{}
+	}
+
+	jsonData, err := json.MarshalIndent(traces, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	jsContent := fmt.Sprintf("const tracesData = %s;", string(jsonData))
+
+	file, err := os.Create(filepath.Join(g.config.OutputDir, "assets", "traces.js"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(jsContent)
+	return err
+}
+
 func (g *HTMLGenerator) generateNavigatorJS() error {
 	file, err := os.Create(filepath.Join(g.config.OutputDir, "assets", "navigator.js"))
 	if err != nil {
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// TypeParam 関数の型パラメータ
+type TypeParam struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint"`        // 型制約（any、comparable、~int | ~float64、shared.Number など）
+	Diagram    string `json:"diagram,omitempty"` // 型制約がモジュール内のインターフェースなら、それを載せたクラス図のID
+}
+
+// typeParamsOf は関数宣言の型パラメータを、ソースに書かれた型制約とともに返す
+func (a *Analyzer) typeParamsOf(fields *ast.FieldList) []TypeParam {
+	if fields == nil {
+		return nil
+	}
+	var params []TypeParam
+	for _, field := range fields.List {
+		constraint := a.exprToString(field.Type)
+		if constraint == "" {
+			// interface{ ... } を直接書いた型制約
+			constraint = "interface{…}"
+		}
+		diagram := ""
+		if named, ok := a.info.TypeOf(field.Type).(*types.Named); ok && types.IsInterface(named) &&
+			named.Obj().Pkg() != nil && a.isModulePackage(named.Obj().Pkg().Path()) {
+			diagram = a.classDiagramID(named.Obj().Pkg())
+		}
+		for _, name := range field.Names {
+			params = append(params, TypeParam{Name: name.Name, Constraint: constraint, Diagram: diagram})
+		}
+	}
+	return params
+}
+
+// receiverTypeParams はジェネリック型のレシーバー（*Cache[K, V] など）の型パラメータ名を返す
+func receiverTypeParams(expr ast.Expr) []string {
+	var indices []ast.Expr
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeParams(t.X)
+	case *ast.ParenExpr:
+		return receiverTypeParams(t.X)
+	case *ast.IndexExpr:
+		indices = []ast.Expr{t.Index}
+	case *ast.IndexListExpr:
+		indices = t.Indices
+	}
+	var names []string
+	for _, index := range indices {
+		if ident, ok := index.(*ast.Ident); ok {
+			names = append(names, ident.Name)
+		}
+	}
+	return names
+}
+
+// isGenericFunc は式がジェネリック関数（またはそのメソッド値）を指すかを判定する
+// Map[K, V](...) の型引数を取り除いて呼び出し名にするかの判定に使う（スライスやマップの添字は除く）
+func (a *Analyzer) isGenericFunc(expr ast.Expr) bool {
+	var ident *ast.Ident
+	switch e := expr.(type) {
+	case *ast.Ident:
+		ident = e
+	case *ast.SelectorExpr:
+		ident = e.Sel
+	default:
+		return false
+	}
+	_, ok := a.info.Uses[ident].(*types.Func)
+	return ok
+}
+
+// implementsInterface は名前付き型（またはそのポインタ）がインターフェースを実装するかどうかを返す
+// 型引数を与えていないジェネリック型に対する types.Implements の結果は定まらないため、実装とはみなさない
+func implementsInterface(named *types.Named, iface *types.Interface) bool {
+	if named.TypeParams().Len() > 0 {
+		return false
+	}
+	return types.Implements(named, iface) || types.Implements(types.NewPointer(named), iface)
+}
+
+// constraintUnions は型制約のインターフェースに埋め込まれた型集合（~int | ~float64 など）を返す
+func constraintUnions(iface *types.Interface) []*types.Union {
+	var unions []*types.Union
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		if union, ok := iface.EmbeddedType(i).(*types.Union); ok {
+			unions = append(unions, union)
+		}
+	}
+	return unions
+}
+
+// unionString は型集合を「~int | ~float64」の形式で返す
+func (b *classDiagramBuilder) unionString(union *types.Union) string {
+	var terms []string
+	for i := 0; i < union.Len(); i++ {
+		term := union.Term(i)
+		if term.Tilde() {
+			terms = append(terms, "~"+b.typeString(term.Type()))
+		} else {
+			terms = append(terms, b.typeString(term.Type()))
+		}
+	}
+	return strings.Join(terms, " | ")
+}
+
+// termMember は型集合の要素をクラス図のメンバーにする
+// Mermaid のクラス図では ~ がジェネリクスの記法になるため、~int は「int（基底型）」と書く
+func (b *classDiagramBuilder) termMember(term *types.Term) string {
+	if term.Tilde() {
+		return b.typeString(term.Type()) + "（基底型）"
+	}
+	return b.typeString(term.Type())
+}
+
+// typeParamList は型パラメータを「[K comparable, V any]」の形式で返す（ジェネリック型でなければ空文字列）
+func (b *classDiagramBuilder) typeParamList(list *types.TypeParamList) string {
+	if list == nil || list.Len() == 0 {
+		return ""
+	}
+	var params []string
+	for i := 0; i < list.Len(); i++ {
+		param := list.At(i)
+		params = append(params, param.Obj().Name()+" "+b.typeString(param.Constraint()))
+	}
+	return "[" + strings.Join(params, ", ") + "]"
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// findGitRoot は dir から親ディレクトリを遡って .git を持つディレクトリを返す
+func findGitRoot(dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(absDir, ".git")); err == nil {
+			return absDir, nil
+		}
+		parent := filepath.Dir(absDir)
+		if parent == absDir {
+			return "", fmt.Errorf(".git が見つかりません: %s", dir)
+		}
+		absDir = parent
+	}
+}
+
+// resolveGitDir は .git がファイル（worktree・submodule）の場合も含めて実体のディレクトリを返す
+func resolveGitDir(root string) (string, error) {
+	gitPath := filepath.Join(root, ".git")
+	info, err := os.Stat(gitPath)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return gitPath, nil
+	}
+
+	content, err := os.ReadFile(gitPath)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", fmt.Errorf("不正な .git ファイルです: %s", gitPath)
+	}
+	gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(root, gitDir)
+	}
+	return gitDir, nil
+}
+
+// readGitCommit は git コマンドを使わずに .git から HEAD のコミットSHAを読み取る
+func readGitCommit(root string) (string, error) {
+	gitDir, err := resolveGitDir(root)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", err
+	}
+	ref := strings.TrimSpace(string(head))
+	if !strings.HasPrefix(ref, "ref:") {
+		// detached HEAD
+		return ref, nil
+	}
+	ref = strings.TrimSpace(strings.TrimPrefix(ref, "ref:"))
+
+	// worktree の場合、refs は共通ディレクトリにある
+	refDirs := []string{gitDir}
+	if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir := strings.TrimSpace(string(common))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+		refDirs = append(refDirs, commonDir)
+	}
+
+	for _, dir := range refDirs {
+		if sha, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref))); err == nil {
+			return strings.TrimSpace(string(sha)), nil
+		}
+		if sha, ok := lookupPackedRef(filepath.Join(dir, "packed-refs"), ref); ok {
+			return sha, nil
+		}
+	}
+	return "", fmt.Errorf("参照 %s を解決できません", ref)
+}
+
+// lookupPackedRef は packed-refs ファイルから参照のSHAを探す
+func lookupPackedRef(path, ref string) (string, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == ref {
+			return fields[0], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LayerRule パッケージ間の依存を禁止するルール
+type LayerRule struct {
+	From string // 依存元のディレクトリ（例: domain）。配下のパッケージも対象
+	Deny string // 依存してはいけないディレクトリ（例: application）
+}
+
+// packageImport パッケージ間の依存（最初に見つかったインポート文の位置を持つ）
+type packageImport struct {
+	from     string // 依存元のディレクトリ
+	to       string // 依存先のディレクトリ
+	fileName string
+	line     int
+	rule     *LayerRule // 違反しているルール（違反でなければ nil）
+}
+
+// analyzeLayers はモジュール内のパッケージの依存関係図を生成し、レイヤールールの違反を検出する
+func (a *Analyzer) analyzeLayers() {
+	if len(a.modules) == 0 {
+		return
+	}
+
+	dirs, imports, err := a.collectPackageImports()
+	if err != nil {
+		if a.config.Verbose {
+			fmt.Printf("警告: パッケージの依存関係を取得できません (%v)\n", err)
+		}
+		return
+	}
+
+	var violations []*packageImport
+	for _, imp := range imports {
+		for i := range a.config.LayerRules {
+			rule := &a.config.LayerRules[i]
+			if inLayer(imp.from, rule.From) && inLayer(imp.to, rule.Deny) {
+				imp.rule = rule
+				violations = append(violations, imp)
+				a.checkFailures = append(a.checkFailures, fmt.Sprintf("レイヤー違反: %s -> %s (%s は %s に依存できません) %s:%d",
+					imp.from, imp.to, rule.From, rule.Deny, imp.fileName, imp.line))
+				break
+			}
+		}
+	}
+	if a.config.Verbose {
+		for _, imp := range violations {
+			fmt.Printf("警告: レイヤー違反 %s -> %s (%s:%d)\n", imp.from, imp.to, imp.fileName, imp.line)
+		}
+	}
+
+	a.diagrams = append(a.diagrams, a.layerDiagram(dirs, imports, violations))
+}
+
+// collectPackageImports はモジュール内の全パッケージのディレクトリと、パッケージ間のインポートを集める
+func (a *Analyzer) collectPackageImports() ([]string, []*packageImport, error) {
+	dirSet := make(map[string]bool)
+	importIndex := make(map[string]*packageImport)
+	var imports []*packageImport
+
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != "." && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata" || path == a.config.OutputDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(a.fileSet, path, nil, parser.ImportsOnly)
+		if err != nil {
+			// 構文エラーのファイルは検出結果として報告し、残りのファイルで依存関係を求める
+			a.checkFailures = append(a.checkFailures, fmt.Sprintf("構文エラー: %s の依存関係を取得できません (%v)", path, err))
+			if a.config.Verbose {
+				fmt.Printf("警告: 構文エラーのため依存関係の解析から除外します %s (%v)\n", path, err)
+			}
+			return nil
+		}
+		from := filepath.ToSlash(filepath.Dir(path))
+		dirSet[from] = true
+		for _, spec := range file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || !a.isModulePackage(importPath) {
+				continue
+			}
+			to := filepath.ToSlash(a.dirOf(importPath))
+			key := from + " " + to
+			if _, ok := importIndex[key]; ok {
+				continue
+			}
+			imp := &packageImport{from: from, to: to, fileName: path, line: a.fileSet.Position(spec.Pos()).Line}
+			importIndex[key] = imp
+			imports = append(imports, imp)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var dirs []string
+	for dir := range dirSet {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	sort.SliceStable(imports, func(i, j int) bool {
+		if imports[i].from != imports[j].from {
+			return imports[i].from < imports[j].from
+		}
+		return imports[i].to < imports[j].to
+	})
+	return dirs, imports, nil
+}
+
+// inLayer はディレクトリがレイヤー（ディレクトリとその配下）に含まれるかどうかを返す
+func inLayer(dir, layer string) bool {
+	layer = strings.TrimSuffix(filepath.ToSlash(layer), "/")
+	return dir == layer || strings.HasPrefix(dir, layer+"/")
+}
+
+// layerDiagram は最上位ディレクトリをレイヤーとしてまとめたパッケージ依存関係図を組み立てる
+// ルールに違反する依存は赤い線で表示する
+func (a *Analyzer) layerDiagram(dirs []string, imports, violations []*packageImport) *Diagram {
+	var buf bytes.Buffer
+	buf.WriteString("flowchart TD\n")
+
+	nodeIDs := make(map[string]string)
+	layers := make(map[string][]string)
+	var layerNames []string
+	for i, dir := range dirs {
+		nodeIDs[dir] = fmt.Sprintf("P%d", i+1)
+		layer := strings.SplitN(dir, "/", 2)[0]
+		if _, ok := layers[layer]; !ok {
+			layerNames = append(layerNames, layer)
+		}
+		layers[layer] = append(layers[layer], dir)
+	}
+
+	for _, layer := range layerNames {
+		buf.WriteString(fmt.Sprintf("    subgraph L_%s[\"%s\"]\n", sanitizeID(layer), layer))
+		for _, dir := range layers[layer] {
+			buf.WriteString(fmt.Sprintf("        %s[\"%s\"]\n", nodeIDs[dir], dir))
+		}
+		buf.WriteString("    end\n")
+	}
+
+	var violationEdges []string
+	for i, imp := range imports {
+		if imp.rule != nil {
+			buf.WriteString(fmt.Sprintf("    %s -->|違反| %s\n", nodeIDs[imp.from], nodeIDs[imp.to]))
+			violationEdges = append(violationEdges, strconv.Itoa(i))
+		} else {
+			buf.WriteString(fmt.Sprintf("    %s --> %s\n", nodeIDs[imp.from], nodeIDs[imp.to]))
+		}
+	}
+	if len(violationEdges) > 0 {
+		buf.WriteString(fmt.Sprintf("    linkStyle %s stroke:#dc3545,stroke-width:3px,color:#dc3545\n", strings.Join(violationEdges, ",")))
+	}
+
+	// クラス図があるパッケージはクリックでクラス図へ移動する
+	for _, dir := range dirs {
+		if a.hasDiagram("class:" + dir) {
+			buf.WriteString(fmt.Sprintf("    click %s href \"#diagram:class:%s\"\n", nodeIDs[dir], dir))
+		}
+	}
+
+	violationTable := DiagramTable{
+		Title:   "レイヤールールの違反",
+		Columns: []string{"依存元", "依存先", "ルール", "位置"},
+	}
+	for _, imp := range violations {
+		violationTable.Rows = append(violationTable.Rows, []DiagramCell{
+			{Text: imp.from},
+			{Text: imp.to},
+			{Text: fmt.Sprintf("%s は %s に依存できない", imp.rule.From, imp.rule.Deny)},
+			{Text: fmt.Sprintf("%s:%d", imp.fileName, imp.line), FileName: imp.fileName, Line: imp.line},
+		})
+	}
+
+	ruleTable := DiagramTable{
+		Title:   "レイヤールール",
+		Columns: []string{"依存元", "禁止する依存先", "違反件数"},
+	}
+	for i := range a.config.LayerRules {
+		rule := &a.config.LayerRules[i]
+		count := 0
+		for _, imp := range violations {
+			if imp.rule == rule {
+				count++
+			}
+		}
+		ruleTable.Rows = append(ruleTable.Rows, []DiagramCell{
+			{Text: rule.From},
+			{Text: rule.Deny},
+			{Text: strconv.Itoa(count)},
+		})
+	}
+
+	description := "モジュール内のパッケージの依存関係です。"
+	if len(a.config.LayerRules) > 0 {
+		description += fmt.Sprintf("レイヤールールに違反する依存（%d件）を赤い線で表示しています。", len(violations))
+	}
+	tables := []DiagramTable{}
+	if len(violations) > 0 {
+		tables = append(tables, violationTable)
+	}
+	if len(ruleTable.Rows) > 0 {
+		tables = append(tables, ruleTable)
+	}
+	return &Diagram{
+		ID:          "layers",
+		Category:    "アーキテクチャ",
+		Title:       "パッケージ依存関係",
+		Description: description,
+		MermaidCode: buf.String(),
+		Tables:      tables,
+	}
+}
+
+// hasDiagram は指定したIDの図が生成済みかどうかを返す
+func (a *Analyzer) hasDiagram(id string) bool {
+	for _, diagram := range a.diagrams {
+		if diagram.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeID はMermaidのIDに使えない文字を _ に置き換える
+func sanitizeID(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -19,11 +19,61 @@ func main() {
 			"*_test.go",
 		},
 		OutputDir: "docs",
-		Verbose:   true,
+		// ソースへのリンク先。{commit} {path} {line} {endLine} が置換される
+		//   GitLab: https://gitlab.com/<group>/<project>/-/blob/{commit}/{path}#L{line}-{endLine}
+		//   Gitea:  https://gitea.example.com/<owner>/<repo>/src/commit/{commit}/{path}#L{line}-L{endLine}
+		RepositoryURL: "https://github.com/shibuya-mizuho/logic-mermaid-pages/blob/{commit}/{path}#L{line}-L{endLine}",
+		// 失敗時に補償（ロールバック）が必要な処理の組
+		CompensationPairs: []CompensationPair{
+			{Acquire: "ReserveStock", Compensate: "ReleaseStock"},
+			{Acquire: "ProcessPayment", Compensate: "RefundPayment"},
+		},
+		// 呼び出し先のフローチャートをサブグラフとして展開する深さ（0で展開しない）
+		ExpandDepth: 2,
+		// パッケージ間の依存の禁止ルール（ディレクトリ単位、配下のパッケージも対象）
+		LayerRules: []LayerRule{
+			{From: "domain", Deny: "application"},
+			{From: "domain", Deny: "infrastructure"},
+			{From: "application", Deny: "infrastructure"},
+		},
+		// ドキュメント化する関数の条件（//logic:entrypoint を付けた関数は常に対象、//logic:ignore は常に対象外）
+		// 例: 文が2つ未満の関数を除外する場合は MinStatements: 2
+		Functions: FunctionFilter{},
+		// 関数の指標のしきい値。超えた関数は check モードで失敗にする（0で検査しない）
+		// 例: 循環的複雑度 15、ネストの深さ 4 を上限にする場合は MetricThresholds{Complexity: 15, Nesting: 4}
+		MetricThresholds: MetricThresholds{},
+		// go test -coverprofile の出力。存在すればフローチャートにテストで実行されたかどうかを重ねる
+		CoverProfile: "coverage.out",
+		// logictrace で記録した実行経路。指定するとフローチャート上でステップごとに再生できる
+		// TraceFiles: []string{"traces/*.json"},
+		// 条件ノードの定数に値（列挙値は宣言の行末コメント）を併記する
+		AnnotateConstants: true,
+		Verbose:           true,
 	}
 
 	fmt.Println("Mermaidドキュメント生成を開始します...")
 
+	// go.work（なければ go.mod）のあるディレクトリを基準に TargetFiles などを解決する
+	if err := chdirWorkspaceRoot(); err != nil {
+		log.Fatalf("ワークスペースのルートに移動できません: %v", err)
+	}
+
+	// version モードではリビジョンを版として OutputDir/versions/ 配下に生成する
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		if err := runVersion(config, os.Args[2:]); err != nil {
+			log.Fatalf("版の生成エラー: %v", err)
+		}
+		return
+	}
+
+	// diff モードでは2つのリビジョンを解析し、変更された関数の差分の図を生成する
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if err := runDiff(config, os.Args[2:]); err != nil {
+			log.Fatalf("差分エラー: %v", err)
+		}
+		return
+	}
+
 	// 解析実行
 	analyzer := NewAnalyzer(config)
 	functions, err := analyzer.AnalyzeAllTargetFiles()
@@ -33,9 +83,23 @@ func main() {
 
 	fmt.Printf("解析完了: %d個の関数を検出しました\n", len(functions))
 
+	// check モードではドキュメントを生成せず、検出結果があれば異常終了する
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		failures := analyzer.CheckFailures()
+		for _, failure := range failures {
+			fmt.Println(failure)
+		}
+		if len(failures) > 0 {
+			fmt.Printf("チェック失敗: %d件\n", len(failures))
+			os.Exit(1)
+		}
+		fmt.Println("チェック成功")
+		return
+	}
+
 	// HTML生成
 	generator := NewHTMLGenerator(config)
-	err = generator.GenerateDocumentation(functions)
+	err = generator.GenerateDocumentation(functions, analyzer.Diagrams(), analyzer.Traces())
 	if err != nil {
 		log.Fatalf("生成エラー: %v", err)
 	}
@@ -44,10 +108,20 @@ func main() {
 }
 
 type Config struct {
-	TargetFiles     []string
-	ExcludePatterns []string
-	OutputDir       string
-	Verbose         bool
+	TargetFiles       []string
+	ExcludePatterns   []string
+	OutputDir         string
+	RepositoryURL     string
+	CompensationPairs []CompensationPair
+	ExpandDepth       int
+	LayerRules        []LayerRule
+	AnnotateConstants bool
+	Functions         FunctionFilter
+	MetricThresholds  MetricThresholds
+	CoverProfile      string
+	TraceFiles        []string
+	Version           string // 生成するドキュメントの版の名前（空なら最新として OutputDir の直下に出力する）
+	Verbose           bool
 }
 
 func expandGlob(patterns []string) ([]string, error) {
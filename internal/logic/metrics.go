@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FunctionMetrics 関数の複雑さとリスクの指標
+type FunctionMetrics struct {
+	Complexity    int      `json:"complexity"`         // 循環的複雑度
+	Nesting       int      `json:"nesting"`            // 制御構文の最大のネストの深さ
+	ExitPoints    int      `json:"exitPoints"`         // 出口（return と関数末尾）の数
+	ErrorBranches int      `json:"errorBranches"`      // エラー判定の分岐の数
+	FanOut        int      `json:"fanOut"`             // 呼び出している関数・メソッドの種類数
+	Exceeded      []string `json:"exceeded,omitempty"` // しきい値を超えた指標（JSON のキー名）
+}
+
+// MetricThresholds 指標のしきい値。超えた関数は check モードで失敗にする（0で検査しない）
+type MetricThresholds struct {
+	Complexity    int
+	Nesting       int
+	ExitPoints    int
+	ErrorBranches int
+	FanOut        int
+}
+
+// metricColumn 指標の表示名と値の取り出し方
+type metricColumn struct {
+	key       string
+	label     string
+	value     func(FunctionMetrics) int
+	threshold func(MetricThresholds) int
+}
+
+var metricColumns = []metricColumn{
+	{"complexity", "循環的複雑度", func(m FunctionMetrics) int { return m.Complexity }, func(t MetricThresholds) int { return t.Complexity }},
+	{"nesting", "ネストの深さ", func(m FunctionMetrics) int { return m.Nesting }, func(t MetricThresholds) int { return t.Nesting }},
+	{"exitPoints", "出口の数", func(m FunctionMetrics) int { return m.ExitPoints }, func(t MetricThresholds) int { return t.ExitPoints }},
+	{"errorBranches", "エラー分岐", func(m FunctionMetrics) int { return m.ErrorBranches }, func(t MetricThresholds) int { return t.ErrorBranches }},
+	{"fanOut", "呼び出し先", func(m FunctionMetrics) int { return m.FanOut }, func(t MetricThresholds) int { return t.FanOut }},
+}
+
+// analyzeMetrics はドキュメント化対象の関数ごとに指標を計算し、一覧表としきい値の検査結果を作る
+func (a *Analyzer) analyzeMetrics() {
+	decls := make([]*ast.FuncDecl, 0, len(a.functionsByDecl))
+	for decl := range a.functionsByDecl {
+		decls = append(decls, decl)
+	}
+	sort.Slice(decls, func(i, j int) bool {
+		return a.functionsByDecl[decls[i]].ID < a.functionsByDecl[decls[j]].ID
+	})
+
+	thresholds := a.config.MetricThresholds
+	for _, decl := range decls {
+		funcInfo := a.functionsByDecl[decl]
+		metrics := a.functionMetrics(decl)
+		for _, column := range metricColumns {
+			limit := column.threshold(thresholds)
+			if limit > 0 && column.value(metrics) > limit {
+				metrics.Exceeded = append(metrics.Exceeded, column.key)
+				a.checkFailures = append(a.checkFailures, fmt.Sprintf("しきい値超過: %s の%sが %d です（上限 %d） %s:%d",
+					funcInfo.FullName, column.label, column.value(metrics), limit, funcInfo.FileName, funcInfo.StartLine))
+			}
+		}
+		funcInfo.Metrics = metrics
+	}
+
+	if len(decls) == 0 {
+		return
+	}
+
+	// 複雑度の高い順に並べる（画面では列の見出しで並べ替えられる）
+	sort.SliceStable(decls, func(i, j int) bool {
+		return a.functionsByDecl[decls[i]].Metrics.Complexity > a.functionsByDecl[decls[j]].Metrics.Complexity
+	})
+	table := DiagramTable{
+		Title:    "関数ごとの指標",
+		Columns:  []string{"関数"},
+		Sortable: true,
+	}
+	for _, column := range metricColumns {
+		table.Columns = append(table.Columns, column.label)
+	}
+	table.Columns = append(table.Columns, "位置")
+	for _, decl := range decls {
+		metrics := a.functionsByDecl[decl].Metrics
+		row := []DiagramCell{a.functionCell(decl)}
+		for _, column := range metricColumns {
+			row = append(row, DiagramCell{
+				Text:    strconv.Itoa(column.value(metrics)),
+				Warning: containsString(metrics.Exceeded, column.key),
+			})
+		}
+		row = append(row, a.positionCell(decl.Pos()))
+		table.Rows = append(table.Rows, row)
+	}
+
+	description := "ドキュメント化している関数の複雑さの指標です。列の見出しをクリックすると並べ替えられます。"
+	var limits []string
+	for _, column := range metricColumns {
+		if limit := column.threshold(thresholds); limit > 0 {
+			limits = append(limits, fmt.Sprintf("%s %d", column.label, limit))
+		}
+	}
+	if len(limits) > 0 {
+		description += fmt.Sprintf("しきい値（%s）を超えた値は赤で表示し、check モードでは失敗になります。", strings.Join(limits, "、"))
+	}
+
+	a.diagrams = append(a.diagrams, &Diagram{
+		ID:          "metrics",
+		Category:    "品質",
+		Title:       "複雑度とリスクの指標",
+		Description: description,
+		Tables:      []DiagramTable{table},
+	})
+}
+
+// functionMetrics は関数の構文木から指標を計算する
+// 関数リテラルの本体は別の関数とみなし、どの指標にも含めない
+func (a *Analyzer) functionMetrics(decl *ast.FuncDecl) FunctionMetrics {
+	metrics := FunctionMetrics{
+		Complexity:    cyclomaticComplexity(decl.Body),
+		Nesting:       nestingDepth(decl.Body),
+		ExitPoints:    exitPoints(decl.Body),
+		ErrorBranches: a.errorBranches(decl.Body),
+	}
+
+	callees := make(map[*types.Func]bool)
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		var ident *ast.Ident
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			ident = fun
+		case *ast.SelectorExpr:
+			ident = fun.Sel
+		}
+		if ident != nil {
+			if fn, ok := a.info.Uses[ident].(*types.Func); ok {
+				callees[fn] = true
+			}
+		}
+		return true
+	})
+	metrics.FanOut = len(callees)
+	return metrics
+}
+
+// cyclomaticComplexity は分岐（if・for・case・&&・||）の数 + 1 を返す
+func cyclomaticComplexity(body *ast.BlockStmt) int {
+	complexity := 1
+	if body == nil {
+		return complexity
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if s.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if s.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if s.Op == token.LAND || s.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// nestingDepth は制御構文の最大のネストの深さを返す（else if は同じ深さとして数える）
+func nestingDepth(body *ast.BlockStmt) int {
+	if body == nil {
+		return 0
+	}
+	maxDepth := 0
+	depth := 0
+	elseIfs := make(map[*ast.IfStmt]bool)
+	var stack []bool // 各ノードが深さを増やしたかどうか
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			if stack[len(stack)-1] {
+				depth--
+			}
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		nests := false
+		switch s := n.(type) {
+		case *ast.FuncLit:
+			// 子をたどらないため、終了時の nil の呼び出しもない
+			return false
+		case *ast.IfStmt:
+			if elseIf, ok := s.Else.(*ast.IfStmt); ok {
+				elseIfs[elseIf] = true
+			}
+			nests = !elseIfs[s]
+		case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			nests = true
+		}
+		if nests {
+			depth++
+			if depth > maxDepth {
+				maxDepth = depth
+			}
+		}
+		stack = append(stack, nests)
+		return true
+	})
+	return maxDepth
+}
+
+// errorBranches は err != nil などのエラー判定の if 文（else if を含む）の数を返す
+// //logic:hide で隠したノードも数えるよう、フローチャートではなく構文木から数える
+func (a *Analyzer) errorBranches(body *ast.BlockStmt) int {
+	if body == nil {
+		return 0
+	}
+	count := 0
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.IfStmt:
+			if a.isErrorCheck(s.Cond) {
+				count++
+			}
+		}
+		return true
+	})
+	return count
+}
+
+// exitPoints は return 文の数を返す。最後の文が return でなければ関数末尾も出口として数える
+// 関数リテラル内の return は数えない
+func exitPoints(body *ast.BlockStmt) int {
+	if body == nil {
+		return 0
+	}
+	count := 0
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			count++
+		}
+		return true
+	})
+	if len(body.List) == 0 {
+		return count + 1
+	}
+	if _, ok := body.List[len(body.List)-1].(*ast.ReturnStmt); !ok {
+		count++
+	}
+	return count
+}
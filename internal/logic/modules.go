@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// goModule 解析するワークスペース内のモジュール
+type goModule struct {
+	Path string // go.mod の module パス
+	Dir  string // ワークスペースのルートからの相対ディレクトリ
+}
+
+// chdirWorkspaceRoot はカレントディレクトリから親をたどり、go.work（なければ go.mod）のあるディレクトリに移動する
+// go:generate でパッケージのディレクトリから実行されても、TargetFiles などの相対パスをルートから解決できるようにする
+func chdirWorkspaceRoot() error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	for _, marker := range []string{"go.work", "go.mod"} {
+		for dir := cwd; ; dir = filepath.Dir(dir) {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				if dir == cwd {
+					return nil
+				}
+				return os.Chdir(dir)
+			}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// discoverModules はワークスペースのルートから解析するモジュールを探す
+// go.work があれば use に書かれたモジュールを、なければルートと配下にある go.mod をすべて対象にする
+func discoverModules(root string) ([]goModule, error) {
+	var dirs []string
+	uses, err := readWorkspaceUses(filepath.Join(root, "go.work"))
+	switch {
+	case err == nil:
+		dirs = uses
+	case errors.Is(err, fs.ErrNotExist):
+		dirs, err = findModuleDirs(root)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	var modules []goModule
+	for _, dir := range dirs {
+		modulePath, err := readModulePath(filepath.Join(root, dir))
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, goModule{Path: modulePath, Dir: filepath.Clean(dir)})
+	}
+	// 入れ子のモジュールを先に見つけられるよう、パスの長い順に並べる
+	sort.Slice(modules, func(i, j int) bool {
+		return len(modules[i].Path) > len(modules[j].Path)
+	})
+	return modules, nil
+}
+
+// readWorkspaceUses は go.work の use ディレクティブに書かれたディレクトリを返す（単独の行とブロックの両方に対応）
+func readWorkspaceUses(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var dirs []string
+	inBlock := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			dirs = append(dirs, strings.Trim(line, `"`))
+		case line == "use (":
+			inBlock = true
+		case strings.HasPrefix(line, "use "):
+			dirs = append(dirs, strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "use")), `"`))
+		}
+	}
+	return dirs, scanner.Err()
+}
+
+// findModuleDirs はルートと配下にある go.mod のディレクトリを返す（隠しディレクトリ・vendor・testdata は除く）
+func findModuleDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if name == "go.mod" {
+			rel, err := filepath.Rel(root, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+			dirs = append(dirs, rel)
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// moduleOfPackage はインポートパスが属するモジュールを返す（モジュール外なら nil）
+func (a *Analyzer) moduleOfPackage(importPath string) *goModule {
+	for i := range a.modules {
+		module := &a.modules[i]
+		if importPath == module.Path || strings.HasPrefix(importPath, module.Path+"/") {
+			return module
+		}
+	}
+	return nil
+}
+
+// moduleOfDir はワークスペースのルートからの相対ディレクトリが属するモジュールを返す（モジュール外なら nil）
+func (a *Analyzer) moduleOfDir(dir string) *goModule {
+	dir = filepath.Clean(dir)
+	var found *goModule
+	for i := range a.modules {
+		module := &a.modules[i]
+		if module.Dir != "." && dir != module.Dir && !strings.HasPrefix(dir, module.Dir+string(filepath.Separator)) {
+			continue
+		}
+		// 入れ子のモジュールがあれば、ディレクトリの深い方を優先する
+		if found == nil || found.Dir == "." || len(module.Dir) > len(found.Dir) {
+			found = module
+		}
+	}
+	return found
+}
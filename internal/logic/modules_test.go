@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadWorkspaceUses(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "単独の use",
+			content: "go 1.21\n\nuse ./billing\n",
+			want:    []string{"./billing"},
+		},
+		{
+			name:    "use ブロック",
+			content: "go 1.21\n\nuse (\n\t.\n\t./billing\n\t./tools/gen\n)\n",
+			want:    []string{".", "./billing", "./tools/gen"},
+		},
+		{
+			name:    "引用符とコメント",
+			content: "go 1.21\n\n// use ./old\nuse (\n\t\".\" // ルート\n\n\t\"./billing\"\n)\nuse \"./extra\" // 追加\n",
+			want:    []string{".", "./billing", "./extra"},
+		},
+		{
+			name:    "use 以外のディレクティブは無視",
+			content: "go 1.21\n\ntoolchain go1.21.5\n\nreplace example.com/x => ./x\n\nuse .\n",
+			want:    []string{"."},
+		},
+		{
+			name:    "use がない",
+			content: "go 1.21\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "go.work")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readWorkspaceUses(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readWorkspaceUses() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// go.work がなければ、go.mod を探すよう fs.ErrNotExist を返す
+	if _, err := readWorkspaceUses(filepath.Join(t.TempDir(), "go.work")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("readWorkspaceUses() error = %v, want fs.ErrNotExist", err)
+	}
+}
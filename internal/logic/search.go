@@ -0,0 +1,95 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// searchGramSize は全文検索の索引に使う n-gram の文字数
+// 日本語は単語の区切りがないため、形態素解析の代わりに2文字ずつの索引で候補を絞り込む
+const searchGramSize = 2
+
+// 検索対象の種類
+const (
+	searchFieldName       = "name"       // 関数名
+	searchFieldComment    = "comment"    // ドキュメントコメント
+	searchFieldNode       = "node"       // フローチャートのノードの表示文言
+	searchFieldCall       = "call"       // 呼び出している関数
+	searchFieldIdentifier = "identifier" // 関数で使っている識別子
+)
+
+// SearchDocument 全文検索の対象の1件
+type SearchDocument struct {
+	Function string `json:"function"`       // ドキュメント化された関数（ID）
+	Node     string `json:"node,omitempty"` // ノードの表示文言の場合はノードID
+	Field    string `json:"field"`
+	Text     string `json:"text"`
+}
+
+// SearchIndex 画面の全文検索で使う n-gram の転置索引
+type SearchIndex struct {
+	GramSize  int              `json:"gramSize"`
+	Documents []SearchDocument `json:"documents"`
+	Grams     map[string][]int `json:"grams"` // n-gram -> Documents のインデックス
+}
+
+// buildSearchIndex は関数の名前・コメント・ノード・呼び出し先・識別子から全文検索の索引を作る
+func buildSearchIndex(functions map[string]*FunctionInfo) *SearchIndex {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	index := &SearchIndex{GramSize: searchGramSize, Grams: make(map[string][]int)}
+	add := func(document SearchDocument) {
+		if strings.TrimSpace(document.Text) == "" {
+			return
+		}
+		i := len(index.Documents)
+		index.Documents = append(index.Documents, document)
+		for _, gram := range searchGrams(document.Text) {
+			index.Grams[gram] = append(index.Grams[gram], i)
+		}
+	}
+
+	for _, name := range names {
+		info := functions[name]
+		add(SearchDocument{Function: name, Field: searchFieldName, Text: info.FullName})
+		add(SearchDocument{Function: name, Field: searchFieldComment, Text: info.Comments})
+
+		// ソースに対応するノードのみ対象にする（「合流点」などの構造だけのノードは除く）
+		if info.flowchart != nil {
+			for _, node := range info.flowchart.nodes {
+				nodeID := nodeIDOf(node)
+				if nodeInfo := info.Nodes[nodeID]; nodeInfo == nil || nodeInfo.StartLine == 0 || nodeID == "N1" {
+					continue
+				}
+				add(SearchDocument{Function: name, Node: nodeID, Field: searchFieldNode, Text: nodeLabelText(node)})
+			}
+		}
+
+		add(SearchDocument{Function: name, Field: searchFieldCall, Text: strings.Join(info.CalledFunctions, " ")})
+		add(SearchDocument{Function: name, Field: searchFieldIdentifier, Text: strings.Join(info.Identifiers, " ")})
+	}
+	return index
+}
+
+// searchGrams は小文字にした文字列の n-gram を重複なく返す（n 文字未満ならその文字列自体）
+func searchGrams(text string) []string {
+	runes := []rune(strings.ToLower(text))
+	if len(runes) < searchGramSize {
+		return []string{string(runes)}
+	}
+	var grams []string
+	seen := make(map[string]bool)
+	for i := 0; i+searchGramSize <= len(runes); i++ {
+		gram := string(runes[i : i+searchGramSize])
+		if strings.TrimSpace(gram) == "" || seen[gram] {
+			continue
+		}
+		seen[gram] = true
+		grams = append(grams, gram)
+	}
+	return grams
+}
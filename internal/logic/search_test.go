@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchGrams(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "空文字列", text: "", want: []string{""}},
+		{name: "n 文字未満はそのまま", text: "A", want: []string{"a"}},
+		{name: "n 文字ちょうど", text: "Go", want: []string{"go"}},
+		{name: "小文字にして分割", text: "Order", want: []string{"or", "rd", "de", "er"}},
+		{name: "重複を除く", text: "aaaa", want: []string{"aa"}},
+		{name: "空白だけの n-gram は除く", text: "a  b", want: []string{"a ", " b"}},
+		{name: "日本語", text: "注文作成", want: []string{"注文", "文作", "作成"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchGrams(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("searchGrams(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
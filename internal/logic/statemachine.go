@@ -0,0 +1,645 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// StatusEnum 型付き定数の集合として定義されたステータス（例: entity.OrderStatus）
+type StatusEnum struct {
+	Name   string
+	Values []StatusValue
+
+	named  *types.Named
+	consts map[types.Object]string // 定数オブジェクト -> 定数名
+}
+
+// StatusValue ステータスの定数
+type StatusValue struct {
+	Name    string // 定数名（例: OrderStatusPending）
+	Value   string // 定数の値（例: pending）
+	Comment string
+}
+
+// StateTransition ステータスの定数を代入している箇所から求めた遷移
+type StateTransition struct {
+	From  string // 遷移元の定数名。生成時は "[*]"、特定できない場合は空
+	To    string
+	Guard string // 遷移元を絞り込んだ条件
+	decl  *ast.FuncDecl
+	pos   token.Pos
+}
+
+// stateSet 条件が成り立つときに取りうるステータスの集合（known が false なら任意）
+type stateSet struct {
+	known bool
+	names []string
+}
+
+// guardResult 判定関数が true / false を返すときに取りうるステータス
+type guardResult struct {
+	whenTrue  stateSet
+	whenFalse stateSet
+}
+
+// stateMachine は1つのステータスについて、関数をまたいで遷移と判定関数を集める
+type stateMachine struct {
+	analyzer    *Analyzer
+	enum        *StatusEnum
+	guards      map[*types.Func]*guardResult
+	decl        *ast.FuncDecl
+	transitions []StateTransition
+}
+
+// analyzeStateMachines はステータスの定数ごとに状態遷移図を生成する
+func (a *Analyzer) analyzeStateMachines() {
+	for _, enum := range a.findStatusEnums() {
+		sm := &stateMachine{analyzer: a, enum: enum, guards: make(map[*types.Func]*guardResult)}
+		for _, decl := range a.moduleFuncDecls() {
+			sm.collect(decl)
+		}
+		if !sm.hasStateChange() {
+			// 生成時の指定しかない定数（カテゴリなど）は状態として扱わない
+			continue
+		}
+		a.diagrams = append(a.diagrams, sm.diagram())
+		if a.config.Verbose {
+			fmt.Printf("状態遷移: %s (%d件)\n", enum.Name, len(sm.transitions))
+		}
+	}
+}
+
+// findStatusEnums は文字列・整数を基底型とする名前付き型のうち、
+// 同じパッケージに2つ以上の定数が宣言されているものを返す
+func (a *Analyzer) findStatusEnums() []*StatusEnum {
+	var enums []*StatusEnum
+	for _, path := range a.modulePackagePaths() {
+		pkg := a.packages[path]
+		comments := a.constComments(path)
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || obj.IsAlias() {
+				continue
+			}
+			named, ok := obj.Type().(*types.Named)
+			if !ok {
+				continue
+			}
+			basic, ok := named.Underlying().(*types.Basic)
+			if !ok || basic.Info()&(types.IsString|types.IsInteger) == 0 {
+				continue
+			}
+
+			var consts []*types.Const
+			for _, constName := range scope.Names() {
+				if c, ok := scope.Lookup(constName).(*types.Const); ok && types.Identical(c.Type(), named) {
+					consts = append(consts, c)
+				}
+			}
+			if len(consts) < 2 {
+				continue
+			}
+			sort.Slice(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+
+			enum := &StatusEnum{Name: typeName(named), named: named, consts: make(map[types.Object]string)}
+			for _, c := range consts {
+				value := c.Val().ExactString()
+				if c.Val().Kind() == constant.String {
+					value = constant.StringVal(c.Val())
+				}
+				enum.Values = append(enum.Values, StatusValue{Name: c.Name(), Value: value, Comment: comments[c.Pos()].label()})
+				enum.consts[c] = c.Name()
+			}
+			enums = append(enums, enum)
+		}
+	}
+	return enums
+}
+
+// modulePackagePaths は型チェック済みのモジュール内パッケージのインポートパスを返す
+func (a *Analyzer) modulePackagePaths() []string {
+	var paths []string
+	for path, pkg := range a.packages {
+		if pkg != nil {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// moduleFuncDecls はモジュール内パッケージの関数宣言をパッケージ・ファイル順に返す
+func (a *Analyzer) moduleFuncDecls() []*ast.FuncDecl {
+	var decls []*ast.FuncDecl
+	for _, path := range a.modulePackagePaths() {
+		for _, file := range a.packageFiles[path] {
+			for _, decl := range file.Decls {
+				if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Body != nil {
+					decls = append(decls, funcDecl)
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// constComment 定数宣言のコメント
+type constComment struct {
+	doc  string // 直前のコメント
+	line string // 行末のコメント
+}
+
+// label は定数の表示名として使うコメント（行末のコメントを優先）を返す
+func (c constComment) label() string {
+	if c.line != "" {
+		return c.line
+	}
+	return c.doc
+}
+
+// constComments は定数の宣言位置 -> コメントを返す
+func (a *Analyzer) constComments(path string) map[token.Pos]constComment {
+	if comments, ok := a.constCommentCache[path]; ok {
+		return comments
+	}
+	comments := make(map[token.Pos]constComment)
+	a.constCommentCache[path] = comments
+	for _, file := range a.packageFiles[path] {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				valueSpec := spec.(*ast.ValueSpec)
+				var comment constComment
+				if valueSpec.Doc != nil {
+					comment.doc = strings.TrimSpace(valueSpec.Doc.Text())
+				} else if len(genDecl.Specs) == 1 && genDecl.Doc != nil {
+					comment.doc = strings.TrimSpace(genDecl.Doc.Text())
+				}
+				if valueSpec.Comment != nil {
+					comment.line = strings.TrimSpace(valueSpec.Comment.Text())
+				}
+				for _, name := range valueSpec.Names {
+					comments[name.Pos()] = comment
+				}
+			}
+		}
+	}
+	return comments
+}
+
+// collect は関数本体からステータスの代入と生成を集める
+func (sm *stateMachine) collect(decl *ast.FuncDecl) {
+	sm.decl = decl
+	sm.walkStmts(decl.Body.List, stateSet{}, nil)
+
+	// 構造体リテラルでのステータス指定は生成時の初期状態とみなす
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			if to := sm.constOf(kv.Value); to != "" && sm.isFieldOfEnum(kv.Key) {
+				sm.add(StateTransition{From: "[*]", To: to, pos: kv.Pos()})
+			}
+		}
+		return true
+	})
+}
+
+// hasStateChange は生成後にステータスを変更する遷移があるかどうかを返す
+func (sm *stateMachine) hasStateChange() bool {
+	for _, t := range sm.transitions {
+		if t.From != "[*]" {
+			return true
+		}
+	}
+	return false
+}
+
+func (sm *stateMachine) add(t StateTransition) {
+	t.decl = sm.decl
+	for _, existing := range sm.transitions {
+		if existing.decl == t.decl && existing.From == t.From && existing.To == t.To {
+			return
+		}
+	}
+	sm.transitions = append(sm.transitions, t)
+}
+
+// walkStmts は文を順にたどり、各時点で取りうるステータスを絞り込みながら代入を記録する
+func (sm *stateMachine) walkStmts(stmts []ast.Stmt, from stateSet, guards []string) {
+	for _, stmt := range stmts {
+		from, guards = sm.walkStmt(stmt, from, guards)
+	}
+}
+
+// walkStmt は文をたどり、後続の文で取りうるステータスと条件を返す
+func (sm *stateMachine) walkStmt(stmt ast.Stmt, from stateSet, guards []string) (stateSet, []string) {
+	a := sm.analyzer
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		for i, lhs := range s.Lhs {
+			if i >= len(s.Rhs) || !sm.isEnumVariable(lhs) {
+				continue
+			}
+			to := sm.constOf(s.Rhs[i])
+			if to == "" {
+				continue
+			}
+			sm.record(from, to, guards, s.Pos())
+			from = stateSet{known: true, names: []string{to}}
+			guards = nil
+		}
+	case *ast.IfStmt:
+		if s.Init != nil {
+			from, guards = sm.walkStmt(s.Init, from, guards)
+		}
+		result := sm.condition(s.Cond)
+		cond := a.exprToString(s.Cond)
+
+		bodyGuards, elseGuards := guards, guards
+		if result.whenTrue.known {
+			bodyGuards = appendCondition(guards, cond)
+		}
+		if result.whenFalse.known {
+			elseGuards = appendCondition(guards, negateCondition(cond))
+		}
+		sm.walkStmts(s.Body.List, intersectStates(from, result.whenTrue), bodyGuards)
+		if s.Else != nil {
+			sm.walkStmt(s.Else, intersectStates(from, result.whenFalse), elseGuards)
+		} else if terminates(s.Body) {
+			// 早期リターンの後は条件が成り立たない
+			return intersectStates(from, result.whenFalse), elseGuards
+		}
+	case *ast.SwitchStmt:
+		if s.Init != nil {
+			from, guards = sm.walkStmt(s.Init, from, guards)
+		}
+		enumTag := s.Tag != nil && sm.isEnumVariable(s.Tag)
+		var listed []string
+		for _, clause := range s.Body.List {
+			for _, expr := range clause.(*ast.CaseClause).List {
+				if name := sm.constOf(expr); name != "" {
+					listed = append(listed, name)
+				}
+			}
+		}
+		for _, clause := range s.Body.List {
+			cc := clause.(*ast.CaseClause)
+			caseStates := stateSet{}
+			if enumTag {
+				caseStates = stateSet{known: true}
+				for _, expr := range cc.List {
+					if name := sm.constOf(expr); name != "" {
+						caseStates.names = append(caseStates.names, name)
+					}
+				}
+				if len(cc.List) == 0 {
+					// default 節は他の case に挙げられていないステータス
+					caseStates = sm.complement(stateSet{known: true, names: listed})
+				}
+			}
+			cond := caseCondition(a, a.exprToString(s.Tag), cc.List)
+			sm.walkStmts(cc.Body, intersectStates(from, caseStates), appendCondition(guards, cond))
+		}
+	case *ast.BlockStmt:
+		sm.walkStmts(s.List, from, guards)
+	case *ast.ForStmt:
+		sm.walkStmts(s.Body.List, from, guards)
+	case *ast.RangeStmt:
+		sm.walkStmts(s.Body.List, from, guards)
+	case *ast.LabeledStmt:
+		return sm.walkStmt(s.Stmt, from, guards)
+	}
+	return from, guards
+}
+
+// record は取りうる遷移元ごとに遷移を記録する
+func (sm *stateMachine) record(from stateSet, to string, guards []string, pos token.Pos) {
+	guard := strings.Join(guards, " && ")
+	if !from.known {
+		sm.add(StateTransition{To: to, Guard: guard, pos: pos})
+		return
+	}
+	for _, name := range from.names {
+		sm.add(StateTransition{From: name, To: to, Guard: guard, pos: pos})
+	}
+}
+
+// condition は条件式が成り立つとき・成り立たないときに取りうるステータスを求める
+func (sm *stateMachine) condition(expr ast.Expr) guardResult {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return sm.condition(e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.NOT {
+			result := sm.condition(e.X)
+			return guardResult{whenTrue: result.whenFalse, whenFalse: result.whenTrue}
+		}
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.EQL, token.NEQ:
+			name := ""
+			if sm.isEnumVariable(e.X) {
+				name = sm.constOf(e.Y)
+			} else if sm.isEnumVariable(e.Y) {
+				name = sm.constOf(e.X)
+			}
+			if name == "" {
+				return guardResult{}
+			}
+			equal := stateSet{known: true, names: []string{name}}
+			result := guardResult{whenTrue: equal, whenFalse: sm.complement(equal)}
+			if e.Op == token.NEQ {
+				result.whenTrue, result.whenFalse = result.whenFalse, result.whenTrue
+			}
+			return result
+		case token.LAND:
+			x, y := sm.condition(e.X), sm.condition(e.Y)
+			return guardResult{
+				whenTrue:  intersectStates(x.whenTrue, y.whenTrue),
+				whenFalse: unionStates(x.whenFalse, y.whenFalse),
+			}
+		case token.LOR:
+			x, y := sm.condition(e.X), sm.condition(e.Y)
+			return guardResult{
+				whenTrue:  unionStates(x.whenTrue, y.whenTrue),
+				whenFalse: intersectStates(x.whenFalse, y.whenFalse),
+			}
+		}
+	case *ast.CallExpr:
+		if fn := sm.analyzer.calleeObject(e.Fun); fn != nil {
+			if result := sm.guardOf(fn); result != nil {
+				return *result
+			}
+		}
+	}
+	return guardResult{}
+}
+
+// guardOf は bool を返す判定関数（例: Order.CanCancel）が true / false を返すときに取りうるステータスを求める
+func (sm *stateMachine) guardOf(fn *types.Func) *guardResult {
+	if result, ok := sm.guards[fn]; ok {
+		return result
+	}
+	decl, ok := sm.analyzer.funcDecls[fn]
+	if !ok || decl.Body == nil || !returnsBool(fn) {
+		return nil
+	}
+
+	// 再帰呼び出しは任意のステータスとして扱う
+	sm.guards[fn] = &guardResult{}
+
+	result := &guardResult{}
+	stmts := decl.Body.List
+	for i, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.IfStmt:
+			// if 条件 { return false } の後は条件が成り立たない
+			if s.Init == nil && s.Else == nil && returnsFalse(s.Body) {
+				result.whenTrue = intersectStates(result.whenTrue, sm.condition(s.Cond).whenFalse)
+			}
+		case *ast.ReturnStmt:
+			if len(s.Results) != 1 {
+				continue
+			}
+			cond := sm.condition(s.Results[0])
+			result.whenTrue = intersectStates(result.whenTrue, cond.whenTrue)
+			if i == 0 {
+				result.whenFalse = cond.whenFalse
+			}
+		}
+	}
+	sm.guards[fn] = result
+	return result
+}
+
+// constOf は式がこのステータスの定数であればその定数名を返す
+func (sm *stateMachine) constOf(expr ast.Expr) string {
+	var ident *ast.Ident
+	switch e := expr.(type) {
+	case *ast.Ident:
+		ident = e
+	case *ast.SelectorExpr:
+		ident = e.Sel
+	case *ast.ParenExpr:
+		return sm.constOf(e.X)
+	default:
+		return ""
+	}
+	return sm.enum.consts[sm.analyzer.info.Uses[ident]]
+}
+
+// isEnumVariable は式が定数ではないこのステータス型の値（フィールド・変数）かどうかを返す
+func (sm *stateMachine) isEnumVariable(expr ast.Expr) bool {
+	tv, ok := sm.analyzer.info.Types[expr]
+	return ok && tv.Value == nil && types.Identical(tv.Type, sm.enum.named)
+}
+
+// isFieldOfEnum は構造体リテラルのキーがこのステータス型のフィールドかどうかを返す
+func (sm *stateMachine) isFieldOfEnum(key ast.Expr) bool {
+	ident, ok := key.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	field, ok := sm.analyzer.info.Uses[ident].(*types.Var)
+	return ok && field.IsField() && types.Identical(field.Type(), sm.enum.named)
+}
+
+// complement は集合に含まれないステータスを返す
+func (sm *stateMachine) complement(set stateSet) stateSet {
+	if !set.known {
+		return stateSet{}
+	}
+	result := stateSet{known: true}
+	for _, value := range sm.enum.Values {
+		if !containsString(set.names, value.Name) {
+			result.names = append(result.names, value.Name)
+		}
+	}
+	return result
+}
+
+// diagram は状態遷移図と遷移・判定関数の一覧を組み立てる
+func (sm *stateMachine) diagram() *Diagram {
+	a := sm.analyzer
+	var buf bytes.Buffer
+	buf.WriteString("stateDiagram-v2\n")
+	for _, value := range sm.enum.Values {
+		label := value.Value
+		if value.Comment != "" {
+			label += "（" + value.Comment + "）"
+		}
+		buf.WriteString(fmt.Sprintf("    state \"%s\" as %s\n", escapeStateLabel(label), value.Name))
+	}
+
+	hasUnknown := false
+	for _, t := range sm.transitions {
+		if t.From == "" {
+			hasUnknown = true
+		}
+	}
+	if hasUnknown {
+		buf.WriteString("    state \"任意の状態\" as AnyState\n")
+		buf.WriteString("    classDef unknownState fill:#f8f9fa,stroke:#adb5bd,stroke-dasharray:4 4,color:#6c757d\n")
+		buf.WriteString("    class AnyState unknownState\n")
+	}
+
+	transitionTable := DiagramTable{
+		Title:   "遷移一覧",
+		Columns: []string{"遷移元", "遷移先", "関数", "条件", "位置"},
+	}
+	for _, t := range sm.transitions {
+		from := t.From
+		fromLabel := from
+		switch from {
+		case "":
+			from, fromLabel = "AnyState", "任意の状態"
+		case "[*]":
+			fromLabel = "（生成時）"
+		}
+		function := a.functionCell(t.decl)
+		buf.WriteString(fmt.Sprintf("    %s --> %s : %s\n", from, t.To, escapeStateLabel(shortFunctionName(function.Text))))
+
+		transitionTable.Rows = append(transitionTable.Rows, []DiagramCell{
+			{Text: fromLabel},
+			{Text: t.To},
+			function,
+			{Text: t.Guard},
+			a.positionCell(t.pos),
+		})
+	}
+
+	guardTable := DiagramTable{
+		Title:   "ステータスの判定関数",
+		Columns: []string{"関数", "true になるステータス", "位置"},
+	}
+	for _, decl := range a.moduleFuncDecls() {
+		fn, ok := a.info.Defs[decl.Name].(*types.Func)
+		if !ok {
+			continue
+		}
+		result := sm.guardOf(fn)
+		if result == nil || !result.whenTrue.known {
+			continue
+		}
+		guardTable.Rows = append(guardTable.Rows, []DiagramCell{
+			a.functionCell(decl),
+			{Text: strings.Join(result.whenTrue.names, ", ")},
+			a.positionCell(decl.Pos()),
+		})
+	}
+
+	tables := []DiagramTable{transitionTable}
+	if len(guardTable.Rows) > 0 {
+		tables = append(tables, guardTable)
+	}
+	return &Diagram{
+		ID:       "state:" + sm.enum.Name,
+		Category: "状態遷移図",
+		Title:    sm.enum.Name,
+		Description: sm.enum.Name + " の定数を代入している箇所から求めた状態遷移です。" +
+			"遷移元はステータスの比較や判定関数の呼び出しから推定し、特定できない場合は「任意の状態」としています。",
+		MermaidCode: buf.String(),
+		Tables:      tables,
+	}
+}
+
+// shortFunctionName はパッケージ名を除いた関数名を返す（例: OrderRefundUseCase.RefundOrder）
+func shortFunctionName(name string) string {
+	if strings.Count(name, ".") >= 2 {
+		return name[strings.Index(name, ".")+1:]
+	}
+	return name
+}
+
+func escapeStateLabel(s string) string {
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	s = strings.ReplaceAll(s, ":", "#colon;")
+	return s
+}
+
+func negateCondition(cond string) string {
+	if strings.HasPrefix(cond, "!") && !strings.ContainsAny(cond, "&|") {
+		return strings.TrimPrefix(cond, "!")
+	}
+	return "!(" + cond + ")"
+}
+
+// terminates はブロックが return などで終わり、後続の文に到達しないかどうかを返す
+func terminates(block *ast.BlockStmt) bool {
+	if len(block.List) == 0 {
+		return false
+	}
+	switch s := block.List[len(block.List)-1].(type) {
+	case *ast.ReturnStmt, *ast.BranchStmt:
+		return true
+	case *ast.ExprStmt:
+		if call, ok := s.X.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == "panic" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// returnsFalse はブロックが return false だけかどうかを返す
+func returnsFalse(block *ast.BlockStmt) bool {
+	if len(block.List) != 1 {
+		return false
+	}
+	ret, ok := block.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return false
+	}
+	ident, ok := ret.Results[0].(*ast.Ident)
+	return ok && ident.Name == "false"
+}
+
+func returnsBool(fn *types.Func) bool {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Results().Len() != 1 {
+		return false
+	}
+	basic, ok := sig.Results().At(0).Type().(*types.Basic)
+	return ok && basic.Kind() == types.Bool
+}
+
+func intersectStates(x, y stateSet) stateSet {
+	if !x.known {
+		return y
+	}
+	if !y.known {
+		return x
+	}
+	result := stateSet{known: true}
+	for _, name := range x.names {
+		if containsString(y.names, name) {
+			result.names = append(result.names, name)
+		}
+	}
+	return result
+}
+
+func unionStates(x, y stateSet) stateSet {
+	if !x.known || !y.known {
+		return stateSet{}
+	}
+	return stateSet{known: true, names: mergeNames(x.names, y.names)}
+}
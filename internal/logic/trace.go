@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shibuya-mizuho/logic-mermaid-pages/internal/logictrace"
+)
+
+// traceHelperPath は実行経路を記録するヘルパーパッケージのインポートパスの末尾
+// このパッケージの呼び出しはフローチャートに表示しない
+const traceHelperPath = "/internal/logictrace"
+
+// TraceStep 実行経路の1ステップ（フローチャート上のノード）
+type TraceStep struct {
+	Function string `json:"function"` // ドキュメント化された関数（ID）
+	Node     string `json:"node"`     // Function のフローチャート上のノードID
+	FileName string `json:"fileName"`
+	Line     int    `json:"line"`
+}
+
+// ExecutionTrace 名前を付けた実行経路
+type ExecutionTrace struct {
+	Name  string      `json:"name"`
+	Steps []TraceStep `json:"steps"`
+}
+
+// analyzeTraces は logictrace で記録した実行経路を読み込み、フローチャート上のノードの列に変換する
+func (a *Analyzer) analyzeTraces() error {
+	files, err := expandGlob(a.config.TraceFiles)
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, fileName := range files {
+		trace, err := loadTrace(fileName)
+		if err != nil {
+			if a.config.Verbose {
+				fmt.Printf("警告: 実行経路を読み込めません %s (%v)\n", fileName, err)
+			}
+			continue
+		}
+
+		executionTrace := &ExecutionTrace{Name: trace.Name}
+		for _, position := range trace.Positions {
+			step, ok := a.traceStep(position)
+			if !ok {
+				// 記録後にソースが編集されると、行がずれてノードに当たらなくなる
+				if a.config.Verbose {
+					fmt.Printf("警告: 実行経路 %s の %s:%d に対応するノードがありません\n", fileName, position.File, position.Line)
+				}
+				continue
+			}
+			// 同じノードが続く場合は1ステップにまとめる
+			if n := len(executionTrace.Steps); n > 0 {
+				last := executionTrace.Steps[n-1]
+				if last.Function == step.Function && last.Node == step.Node {
+					continue
+				}
+			}
+			executionTrace.Steps = append(executionTrace.Steps, step)
+		}
+		if len(executionTrace.Steps) == 0 {
+			if a.config.Verbose {
+				fmt.Printf("警告: 実行経路 %s にドキュメント化された関数の位置がありません\n", fileName)
+			}
+			continue
+		}
+		a.traces = append(a.traces, executionTrace)
+	}
+	return nil
+}
+
+// Traces は読み込んだ実行経路を返す
+func (a *Analyzer) Traces() []*ExecutionTrace {
+	return a.traces
+}
+
+// loadTrace は実行経路の JSON を読み込む
+// logictrace.Save の出力のほか、位置の配列だけのファイル（名前はファイル名になる）も受け付ける
+func loadTrace(fileName string) (*logictrace.Trace, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var trace logictrace.Trace
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		err = json.Unmarshal(data, &trace.Positions)
+	} else {
+		err = json.Unmarshal(data, &trace)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if trace.Name == "" {
+		trace.Name = strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	}
+	return &trace, nil
+}
+
+// traceStep は実行した位置を含む関数とノードを返す
+// logictrace.Mark の行はフローチャートに表示しないため、その後に始まる最初のノードにする
+// それ以外でノードに含まれない位置（フローチャートに表示しない文やドキュメント化されていない関数）は false を返す
+func (a *Analyzer) traceStep(position logictrace.Position) (TraceStep, bool) {
+	fileName := filepath.Clean(position.File)
+	if filepath.IsAbs(fileName) {
+		if cwd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(cwd, fileName); err == nil {
+				fileName = rel
+			}
+		}
+	}
+
+	for _, funcInfo := range a.functions {
+		if funcInfo.FileName != fileName || position.Line < funcInfo.StartLine || position.Line > funcInfo.EndLine {
+			continue
+		}
+		nodeID := nodeAtLine(funcInfo, position.Line)
+		if nodeID == "" && a.traceCalls[logictrace.Position{File: fileName, Line: position.Line}] {
+			nodeID = nodeAfterLine(funcInfo, position.Line)
+		}
+		if nodeID == "" {
+			return TraceStep{}, false
+		}
+		return TraceStep{
+			Function: funcInfo.ID,
+			Node:     nodeID,
+			FileName: fileName,
+			Line:     position.Line,
+		}, true
+	}
+	return TraceStep{}, false
+}
+
+// nodeAfterLine は指定した行より後に始まる最初のノードを返す
+func nodeAfterLine(funcInfo *FunctionInfo, line int) string {
+	best := ""
+	bestLine := 0
+	for nodeID, info := range funcInfo.Nodes {
+		if info.StartLine <= line {
+			continue
+		}
+		if best == "" || info.StartLine < bestLine || (info.StartLine == bestLine && nodeID < best) {
+			best, bestLine = nodeID, info.StartLine
+		}
+	}
+	return best
+}
+
+// isTraceCall は文が logictrace の呼び出しかどうかを返す
+func (a *Analyzer) isTraceCall(stmt ast.Stmt) bool {
+	exprStmt, ok := stmt.(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	call, ok := exprStmt.X.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := selector.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	pkgName, ok := a.info.Uses[ident].(*types.PkgName)
+	return ok && strings.HasSuffix(pkgName.Imported().Path(), traceHelperPath)
+}
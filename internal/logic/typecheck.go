@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// moduleImporter はモジュール内のパッケージを Analyzer で型チェックし、
+// それ以外（標準ライブラリ・外部モジュール）をソースインポーターに委譲する
+type moduleImporter struct {
+	analyzer *Analyzer
+	fallback types.Importer
+}
+
+func (m *moduleImporter) Import(path string) (*types.Package, error) {
+	if m.analyzer.isModulePackage(path) {
+		return m.analyzer.loadPackage(path)
+	}
+	return m.fallback.Import(path)
+}
+
+// readModulePath は go.mod から module パスを読み取る
+func readModulePath(dir string) (string, error) {
+	file, err := os.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module")), `"`), nil
+		}
+	}
+	return "", fmt.Errorf("module 宣言が見つかりません: %s", filepath.Join(dir, "go.mod"))
+}
+
+// typeCheck は解析対象ファイルをパッケージ単位で型チェックし、型情報を a.info に記録する
+// 型チェックに失敗しても解析は継続する（型情報を使う機能が部分的に働かなくなるだけ）
+func (a *Analyzer) typeCheck() {
+	modules, err := discoverModules(".")
+	if err == nil && len(modules) == 0 {
+		err = fmt.Errorf("go.work と go.mod が見つかりません")
+	}
+	if err != nil {
+		if a.config.Verbose {
+			fmt.Printf("警告: モジュールを読み取れないため型情報なしで解析します (%v)\n", err)
+		}
+		return
+	}
+	a.modules = modules
+	a.importer = &moduleImporter{
+		analyzer: a,
+		fallback: importer.ForCompiler(a.fileSet, "source", nil),
+	}
+
+	// 解析対象ファイルのディレクトリをパッケージとして読み込む
+	dirs := make(map[string]bool)
+	for fileName := range a.files {
+		dirs[filepath.Dir(fileName)] = true
+	}
+	var sortedDirs []string
+	for dir := range dirs {
+		sortedDirs = append(sortedDirs, dir)
+	}
+	sort.Strings(sortedDirs)
+
+	for _, dir := range sortedDirs {
+		importPath := a.importPathOf(dir)
+		if importPath == "" {
+			if a.config.Verbose {
+				fmt.Printf("警告: モジュールに属さないため型チェックをスキップします: %s\n", dir)
+			}
+			continue
+		}
+		if _, err := a.loadPackage(importPath); err != nil && a.config.Verbose {
+			fmt.Printf("警告: 型チェックに失敗しました: %s (%v)\n", dir, err)
+		}
+	}
+}
+
+// isModulePackage はワークスペース内のいずれかのモジュールのパッケージかどうかを判定する
+func (a *Analyzer) isModulePackage(path string) bool {
+	return a.moduleOfPackage(path) != nil
+}
+
+// importPathOf はワークスペースのルートからの相対ディレクトリをインポートパスに変換する（モジュール外なら空文字列）
+func (a *Analyzer) importPathOf(dir string) string {
+	module := a.moduleOfDir(dir)
+	if module == nil {
+		return ""
+	}
+	rel, err := filepath.Rel(module.Dir, filepath.Clean(dir))
+	if err != nil {
+		return ""
+	}
+	if rel == "." {
+		return module.Path
+	}
+	return module.Path + "/" + filepath.ToSlash(rel)
+}
+
+// dirOf はインポートパスをワークスペースのルートからの相対ディレクトリに変換する
+func (a *Analyzer) dirOf(importPath string) string {
+	module := a.moduleOfPackage(importPath)
+	if module == nil {
+		return "."
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(importPath, module.Path), "/")
+	return filepath.Join(module.Dir, filepath.FromSlash(rel))
+}
+
+// loadPackage はモジュール内のパッケージを解析・型チェックする（結果はキャッシュする）
+func (a *Analyzer) loadPackage(importPath string) (*types.Package, error) {
+	if pkg, ok := a.packages[importPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("循環インポート: %s", importPath)
+		}
+		return pkg, nil
+	}
+	a.packages[importPath] = nil
+
+	dir := a.dirOf(importPath)
+	files, err := a.parsePackageFiles(dir)
+	if err != nil {
+		delete(a.packages, importPath)
+		return nil, err
+	}
+
+	var typeErrors []error
+	conf := types.Config{
+		Importer: a.importer,
+		Error: func(err error) {
+			typeErrors = append(typeErrors, err)
+		},
+	}
+	pkg, _ := conf.Check(importPath, a.fileSet, files, a.info)
+	if len(typeErrors) > 0 && a.config.Verbose {
+		fmt.Printf("警告: 型エラー %d件: %s (%v)\n", len(typeErrors), importPath, typeErrors[0])
+	}
+
+	a.packages[importPath] = pkg
+	a.packageFiles[importPath] = files
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			if obj, ok := a.info.Defs[funcDecl.Name].(*types.Func); ok {
+				a.funcDecls[obj] = funcDecl
+			}
+		}
+	}
+	return pkg, nil
+}
+
+// parsePackageFiles はディレクトリ内のテスト以外のGoファイルを取得する
+// 解析対象として読み込み済みのファイルはそれを再利用する
+func (a *Analyzer) parsePackageFiles(dir string) ([]*ast.File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		fileName := filepath.Join(dir, name)
+		if file, ok := a.files[fileName]; ok {
+			files = append(files, file)
+			continue
+		}
+		file, err := parser.ParseFile(a.fileSet, fileName, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// typeName はパッケージ名で修飾した型名を返す（例: entity.OrderStateError）
+func typeName(t types.Type) string {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	return types.TypeString(t, func(pkg *types.Package) string {
+		return pkg.Name()
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// versionsDir は OutputDir 配下で版ごとのドキュメントを置くディレクトリ
+const versionsDir = "versions"
+
+// VersionEntry 生成済みの版
+type VersionEntry struct {
+	Name        string `json:"name"`                  // 版の名前（タグやブランチ名）
+	Path        string `json:"path"`                  // OutputDir からの相対パス（末尾は /）
+	Commit      string `json:"commit"`                // 生成元のコミットSHA
+	CommittedAt string `json:"committedAt,omitempty"` // 生成元のコミットの日時（RFC 3339、版の並び順に使う）
+	GeneratedAt string `json:"generatedAt"`           // 生成日時（RFC 3339）
+}
+
+// VersionManifest OutputDir にある版の一覧（versions.json）
+type VersionManifest struct {
+	Versions []VersionEntry `json:"versions"`
+}
+
+// runVersion は version サブコマンドを実行する
+// リビジョンを git worktree に取り出して解析し、OutputDir/versions/<名前>/ に版としてドキュメントを生成する
+//
+//	go run ./internal/logic version v1.2
+//	go run ./internal/logic version --name 旧決済 release/2024-04
+func runVersion(config *Config, args []string) error {
+	flags := flag.NewFlagSet("version", flag.ContinueOnError)
+	name := flags.String("name", "", "版の名前（省略するとリビジョン名）")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("リビジョンを1つ指定してください")
+	}
+	revision := flags.Arg(0)
+	if *name == "" {
+		*name = revision
+	}
+
+	functions, analyzer, err := analyzeRevision(config, revision)
+	if err != nil {
+		return fmt.Errorf("%s の解析に失敗しました: %w", revision, err)
+	}
+
+	versionConfig := *config
+	versionConfig.Version = *name
+	versionConfig.OutputDir = filepath.Join(config.OutputDir, versionPath(*name))
+	generator := NewHTMLGenerator(&versionConfig)
+	// 注釈付きタグはタグオブジェクトではなく、それが指すコミットに解決する
+	commit, err := gitOutput("rev-parse", revision+"^{commit}")
+	if err != nil {
+		return err
+	}
+	committedAt, err := gitOutput("show", "-s", "--format=%cI", commit)
+	if err != nil {
+		return err
+	}
+	if generator.gitRoot != "" {
+		// ソースへのリンクは版のリビジョンを指す
+		generator.commit = commit
+	}
+	if err := generator.GenerateDocumentation(functions, analyzer.Diagrams(), analyzer.Traces()); err != nil {
+		return err
+	}
+
+	err = updateVersionManifest(config.OutputDir, VersionEntry{
+		Name:        *name,
+		Path:        filepath.ToSlash(versionPath(*name)) + "/",
+		Commit:      commit,
+		CommittedAt: committedAt,
+		GeneratedAt: time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("版 %s のドキュメントを生成しました: %s\n", *name, versionConfig.OutputDir)
+	return nil
+}
+
+// sortTime は版を並べるときの日時を返す（コミットの日時を記録していない版は生成日時）
+func (v VersionEntry) sortTime() time.Time {
+	value := v.CommittedAt
+	if value == "" {
+		value = v.GeneratedAt
+	}
+	t, _ := time.Parse(time.RFC3339, value)
+	return t
+}
+
+// versionPath は版のドキュメントを置く OutputDir からの相対パスを返す（ブランチ名の / は - にする）
+func versionPath(name string) string {
+	return filepath.Join(versionsDir, strings.ReplaceAll(name, "/", "-"))
+}
+
+// loadVersionManifest は OutputDir の versions.json を読み込む（なければ空の一覧を返す）
+func loadVersionManifest(outputDir string) (*VersionManifest, error) {
+	manifest := &VersionManifest{}
+	data, err := os.ReadFile(filepath.Join(outputDir, "versions.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("versions.json の形式が不正です: %w", err)
+	}
+	return manifest, nil
+}
+
+// updateVersionManifest は版を一覧に追加（同じ名前があれば置き換え）し、versions.json と versions.js を書き出す
+func updateVersionManifest(outputDir string, entry VersionEntry) error {
+	manifest, err := loadVersionManifest(outputDir)
+	if err != nil {
+		return err
+	}
+	versions := []VersionEntry{entry}
+	for _, version := range manifest.Versions {
+		if version.Name != entry.Name {
+			versions = append(versions, version)
+		}
+	}
+	// 新しいリビジョンの版から順に並べる（古いタグを生成し直しても位置は変わらない）
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].sortTime().After(versions[j].sortTime())
+	})
+	manifest.Versions = versions
+
+	jsonData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "versions.json"), jsonData, 0644); err != nil {
+		return err
+	}
+	return writeVersionsJS(outputDir, jsonData)
+}
+
+// writeVersionsJS は画面の版の切り替えで使う versions.js を書き出す
+// file:// で開いても読み込めるよう、versions.json と同じ内容をスクリプトとして置く
+func writeVersionsJS(outputDir string, jsonData []byte) error {
+	jsContent := fmt.Sprintf("const versionsData = %s;", string(jsonData))
+	return os.WriteFile(filepath.Join(outputDir, "versions.js"), []byte(jsContent), 0644)
+}
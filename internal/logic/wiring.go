@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// wiringComponent は New で始まるコンストラクタで組み立てられる構造体
+type wiringComponent struct {
+	named       *types.Named
+	constructor *ast.FuncDecl
+	deps        []wiringDependency
+}
+
+// wiringDependency はコンストラクタの引数から注入されるフィールド
+type wiringDependency struct {
+	field string
+	named *types.Named // フィールドの型（インターフェースまたは構造体）
+}
+
+// analyzeWiring はコンストラクタと構造体のフィールドから依存性注入の配線図を生成する
+func (a *Analyzer) analyzeWiring() {
+	components := a.findComponents()
+	if len(components) == 0 {
+		return
+	}
+
+	index := make(map[*types.Named]*wiringComponent)
+	for _, c := range components {
+		index[c.named] = c
+	}
+
+	// 依存先の具象型を解決する（インターフェースはコンストラクタを持つ実装型に解決する）
+	resolve := func(dep wiringDependency) []*wiringComponent {
+		if iface, ok := dep.named.Underlying().(*types.Interface); ok {
+			var impls []*wiringComponent
+			for _, c := range components {
+				if implementsInterface(c.named, iface) {
+					impls = append(impls, c)
+				}
+			}
+			return impls
+		}
+		if c, ok := index[dep.named]; ok {
+			return []*wiringComponent{c}
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("flowchart LR\n")
+	nodeIDs := make(map[*types.Named]string)
+	nodeID := func(named *types.Named) string {
+		if id, ok := nodeIDs[named]; ok {
+			return id
+		}
+		id := fmt.Sprintf("W%d", len(nodeIDs)+1)
+		nodeIDs[named] = id
+		return id
+	}
+
+	for _, c := range components {
+		buf.WriteString(fmt.Sprintf("    %s[\"%s\\n%s\"]\n", nodeID(c.named), typeName(c.named), c.constructor.Name.Name))
+	}
+
+	dependents := make(map[*wiringComponent][]*wiringComponent)
+	var edges []string
+	for _, c := range components {
+		for _, dep := range c.deps {
+			impls := resolve(dep)
+			for _, impl := range impls {
+				dependents[impl] = append(dependents[impl], c)
+			}
+			if types.IsInterface(dep.named) {
+				id, declared := nodeIDs[dep.named]
+				if !declared {
+					id = nodeID(dep.named)
+					buf.WriteString(fmt.Sprintf("    %s([\"%s\"])\n", id, typeName(dep.named)))
+				}
+				edges = appendUnique(edges, fmt.Sprintf("%s -->|%s| %s", nodeID(c.named), dep.field, id))
+				for _, impl := range impls {
+					edges = appendUnique(edges, fmt.Sprintf("%s -.->|実装| %s", id, nodeID(impl.named)))
+				}
+			} else {
+				for _, impl := range impls {
+					edges = appendUnique(edges, fmt.Sprintf("%s -->|%s| %s", nodeID(c.named), dep.field, nodeID(impl.named)))
+				}
+			}
+		}
+	}
+	for _, edge := range edges {
+		buf.WriteString("    " + edge + "\n")
+	}
+
+	// コンストラクタがドキュメント化されていればクリックで移動する
+	var interfaces []string
+	for _, c := range components {
+		if funcInfo, ok := a.functionsByDecl[c.constructor]; ok {
+			buf.WriteString(fmt.Sprintf("    click %s href \"#%s\"\n", nodeID(c.named), funcInfo.ID))
+		}
+	}
+	for named, id := range nodeIDs {
+		if types.IsInterface(named) {
+			interfaces = append(interfaces, id)
+		}
+	}
+	sort.Strings(interfaces)
+	if len(interfaces) > 0 {
+		buf.WriteString("    classDef wiringInterface fill:#f8f9fa,stroke:#6c757d,stroke-dasharray:4 4,color:#495057\n")
+		buf.WriteString(fmt.Sprintf("    class %s wiringInterface\n", strings.Join(interfaces, ",")))
+	}
+
+	table := DiagramTable{
+		Title:   "変更の影響範囲",
+		Columns: []string{"型", "コンストラクタ", "注入される依存", "影響を受ける型"},
+	}
+	for _, c := range components {
+		var deps []string
+		for _, dep := range c.deps {
+			var implNames []string
+			for _, impl := range resolve(dep) {
+				implNames = append(implNames, typeName(impl.named))
+			}
+			text := dep.field + ": " + typeName(dep.named)
+			if types.IsInterface(dep.named) && len(implNames) > 0 {
+				text += " → " + strings.Join(implNames, ", ")
+			}
+			deps = append(deps, text)
+		}
+
+		var affected []string
+		for _, dependent := range transitiveDependents(c, dependents) {
+			affected = append(affected, typeName(dependent.named))
+		}
+		sort.Strings(affected)
+
+		table.Rows = append(table.Rows, []DiagramCell{
+			{Text: typeName(c.named)},
+			a.functionCell(c.constructor),
+			{Text: strings.Join(deps, "\n")},
+			{Text: strings.Join(affected, ", ")},
+		})
+	}
+
+	a.diagrams = append(a.diagrams, &Diagram{
+		ID:       "wiring",
+		Category: "アーキテクチャ",
+		Title:    "依存性注入の配線",
+		Description: "New で始まるコンストラクタの引数から注入されるフィールドと、それを満たす具象型の対応です。" +
+			"「影響を受ける型」は、その型を変更したときに直接・間接に依存している型です。",
+		MermaidCode: buf.String(),
+		Tables:      []DiagramTable{table},
+	})
+}
+
+// findComponents は New で始まるコンストラクタと、それが組み立てる構造体を集める
+func (a *Analyzer) findComponents() []*wiringComponent {
+	var components []*wiringComponent
+	seen := make(map[*types.Named]bool)
+	for _, decl := range a.moduleFuncDecls() {
+		if decl.Recv != nil || !strings.HasPrefix(decl.Name.Name, "New") {
+			continue
+		}
+		fn, ok := a.info.Defs[decl.Name].(*types.Func)
+		if !ok {
+			continue
+		}
+		sig := fn.Type().(*types.Signature)
+		if sig.Results().Len() == 0 {
+			continue
+		}
+
+		named := a.constructedType(decl, sig.Results().At(0).Type())
+		if named == nil || seen[named] {
+			continue
+		}
+		seen[named] = true
+
+		params := make(map[types.Object]bool)
+		for i := 0; i < sig.Params().Len(); i++ {
+			params[sig.Params().At(i)] = true
+		}
+		components = append(components, &wiringComponent{
+			named:       named,
+			constructor: decl,
+			deps:        a.injectedFields(decl, named, params),
+		})
+	}
+	return components
+}
+
+// constructedType はコンストラクタが組み立てる構造体を返す
+// 戻り値がインターフェースの場合は return 文の構造体リテラルから求める
+func (a *Analyzer) constructedType(decl *ast.FuncDecl, result types.Type) *types.Named {
+	if named, ok := derefNamed(result); ok {
+		if _, isStruct := named.Underlying().(*types.Struct); isStruct {
+			return named
+		}
+	}
+
+	var found *types.Named
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		ret, ok := n.(*ast.ReturnStmt)
+		if !ok || found != nil || len(ret.Results) == 0 {
+			return found == nil
+		}
+		if named, ok := derefNamed(a.info.TypeOf(ret.Results[0])); ok {
+			if _, isStruct := named.Underlying().(*types.Struct); isStruct {
+				found = named
+			}
+		}
+		return false
+	})
+	return found
+}
+
+// injectedFields はコンストラクタの引数がそのまま代入される、モジュール内の型のフィールドを返す
+func (a *Analyzer) injectedFields(decl *ast.FuncDecl, named *types.Named, params map[types.Object]bool) []wiringDependency {
+	var deps []wiringDependency
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		if litType, ok := derefNamed(a.info.TypeOf(lit)); !ok || litType != named {
+			return true
+		}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			key, keyOK := kv.Key.(*ast.Ident)
+			value, valueOK := kv.Value.(*ast.Ident)
+			if !keyOK || !valueOK || !params[a.info.Uses[value]] {
+				continue
+			}
+			depType, ok := derefNamed(a.info.TypeOf(value))
+			if !ok || depType.Obj().Pkg() == nil || !a.isModulePackage(depType.Obj().Pkg().Path()) || !isClassType(depType) {
+				continue
+			}
+			deps = append(deps, wiringDependency{field: key.Name, named: depType})
+		}
+		return false
+	})
+	return deps
+}
+
+// transitiveDependents は component に直接・間接に依存している型を返す
+func transitiveDependents(component *wiringComponent, dependents map[*wiringComponent][]*wiringComponent) []*wiringComponent {
+	var result []*wiringComponent
+	visited := map[*wiringComponent]bool{component: true}
+	queue := []*wiringComponent{component}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range dependents[current] {
+			if visited[dependent] {
+				continue
+			}
+			visited[dependent] = true
+			result = append(result, dependent)
+			queue = append(queue, dependent)
+		}
+	}
+	return result
+}
+
+func appendUnique(list []string, value string) []string {
+	if containsString(list, value) {
+		return list
+	}
+	return append(list, value)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// 参照されるシンボルの種類
+const (
+	symbolType  = "type"  // モジュール内の名前付き型
+	symbolField = "field" // 構造体のフィールド
+	symbolConst = "const" // パッケージレベルの定数
+)
+
+// 参照の仕方
+const (
+	accessRead  = "read"  // 値の読み取り、型の使用
+	accessWrite = "write" // 代入、複合リテラルでの初期化
+)
+
+// SymbolReference 関数からモジュール内の型・フィールド・定数への参照
+type SymbolReference struct {
+	Symbol string `json:"symbol"` // インポートパスで修飾した名前（.../domain/entity.Payment.RefundAmount など）
+	Label  string `json:"label"`  // パッケージ名で修飾した表示名（entity.Payment.RefundAmount など）
+	Kind   string `json:"kind"`
+	Node   string `json:"node,omitempty"` // 参照している文のノードID
+	Line   int    `json:"line"`
+	Access string `json:"access"`
+}
+
+// XrefEntry シンボルごとの参照の一覧（画面の逆引きで使う）
+type XrefEntry struct {
+	Label      string         `json:"label"`
+	Kind       string         `json:"kind"`
+	References []XrefLocation `json:"references"`
+}
+
+// XrefLocation シンボルを参照している関数とノード
+type XrefLocation struct {
+	Function string `json:"function"`
+	Node     string `json:"node,omitempty"`
+	Line     int    `json:"line"`
+	Access   string `json:"access"`
+}
+
+// analyzeReferences はドキュメント化対象の関数ごとに、モジュール内の型・フィールド・定数の参照を型情報から記録する
+func (a *Analyzer) analyzeReferences() {
+	owners := a.fieldOwners()
+	for decl, funcInfo := range a.functionsByDecl {
+		written := a.writtenIdents(decl)
+		seen := make(map[SymbolReference]bool)
+		ast.Inspect(decl, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			symbol, label, kind := a.symbolOf(a.info.Uses[ident], owners)
+			if symbol == "" {
+				return true
+			}
+			access := accessRead
+			if written[ident] {
+				access = accessWrite
+			}
+			line := a.fileSet.Position(ident.Pos()).Line
+			ref := SymbolReference{Symbol: symbol, Label: label, Kind: kind, Node: nodeAtLine(funcInfo, line), Access: access}
+			// 同じノードでの同じ参照は1件にまとめる（最初の行を残す）
+			if seen[ref] {
+				return true
+			}
+			seen[ref] = true
+			ref.Line = line
+			funcInfo.References = append(funcInfo.References, ref)
+			return true
+		})
+	}
+}
+
+// symbolOf は参照先のオブジェクトが逆引きの対象なら、シンボル名と表示名と種類を返す
+// シンボル名はインポートパスで修飾し、同じ名前のパッケージのシンボルを区別する
+func (a *Analyzer) symbolOf(obj types.Object, owners map[*types.Var]*types.TypeName) (string, string, string) {
+	if obj == nil || obj.Pkg() == nil || !a.isModulePackage(obj.Pkg().Path()) {
+		return "", "", ""
+	}
+	switch obj := obj.(type) {
+	case *types.Var:
+		if !obj.IsField() {
+			return "", "", ""
+		}
+		if owner, ok := owners[obj.Origin()]; ok {
+			symbol, label := qualifiedName(owner)
+			return symbol + "." + obj.Name(), label + "." + obj.Name(), symbolField
+		}
+	case *types.Const:
+		if obj.Parent() == obj.Pkg().Scope() {
+			symbol, label := qualifiedName(obj)
+			return symbol, label, symbolConst
+		}
+	case *types.TypeName:
+		if obj.Parent() == obj.Pkg().Scope() {
+			symbol, label := qualifiedName(obj)
+			return symbol, label, symbolType
+		}
+	}
+	return "", "", ""
+}
+
+// qualifiedName はパッケージレベルの名前を、インポートパスで修飾した名前とパッケージ名で修飾した表示名にする
+func qualifiedName(obj types.Object) (string, string) {
+	return obj.Pkg().Path() + "." + obj.Name(), obj.Pkg().Name() + "." + obj.Name()
+}
+
+// fieldOwners はモジュール内の構造体のフィールド -> 構造体の型名を返す
+func (a *Analyzer) fieldOwners() map[*types.Var]*types.TypeName {
+	owners := make(map[*types.Var]*types.TypeName)
+	for _, named := range a.namedTypes() {
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			owners[st.Field(i)] = named.Obj()
+		}
+	}
+	return owners
+}
+
+// writtenIdents は代入・インクリメント・構造体の複合リテラルのキーで書き込まれる識別子を返す
+func (a *Analyzer) writtenIdents(decl *ast.FuncDecl) map[*ast.Ident]bool {
+	written := make(map[*ast.Ident]bool)
+	mark := func(expr ast.Expr) {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			written[e] = true
+		case *ast.SelectorExpr:
+			written[e.Sel] = true
+		}
+	}
+	ast.Inspect(decl, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.AssignStmt:
+			if s.Tok != token.DEFINE {
+				for _, lhs := range s.Lhs {
+					mark(lhs)
+				}
+			}
+		case *ast.IncDecStmt:
+			mark(s.X)
+		case *ast.CompositeLit:
+			// マップのキーは読み取りなので、構造体の複合リテラルだけを対象にする
+			t := a.info.TypeOf(s)
+			if t == nil {
+				return true
+			}
+			if _, ok := t.Underlying().(*types.Struct); !ok {
+				return true
+			}
+			for _, elt := range s.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					mark(kv.Key)
+				}
+			}
+		}
+		return true
+	})
+	return written
+}
+
+// buildXrefIndex は関数ごとの参照をシンボルごとの逆引きにまとめる
+func buildXrefIndex(functions map[string]*FunctionInfo) map[string]*XrefEntry {
+	index := make(map[string]*XrefEntry)
+	for name, info := range functions {
+		for _, ref := range info.References {
+			entry, ok := index[ref.Symbol]
+			if !ok {
+				entry = &XrefEntry{Label: ref.Label, Kind: ref.Kind}
+				index[ref.Symbol] = entry
+			}
+			entry.References = append(entry.References, XrefLocation{
+				Function: name,
+				Node:     ref.Node,
+				Line:     ref.Line,
+				Access:   ref.Access,
+			})
+		}
+	}
+	for _, entry := range index {
+		sort.Slice(entry.References, func(i, j int) bool {
+			x, y := entry.References[i], entry.References[j]
+			if x.Function != y.Function {
+				return x.Function < y.Function
+			}
+			return x.Line < y.Line
+		})
+	}
+	return index
+}
@@ -0,0 +1,73 @@
+// Package logictrace はビジネスロジックの実行経路を記録し、
+// 生成したドキュメントのフローチャート上で再生できる JSON として書き出す。
+//
+// テストなどで再生したい処理の要所に Mark を入れ、実行後に Save で書き出す。
+//
+//	func TestCreateOrder(t *testing.T) {
+//		logictrace.Reset()
+//		uc.CreateOrder(ctx, req)
+//		logictrace.Save("traces/create_order.json", "注文作成（クーポンあり）")
+//	}
+package logictrace
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// Position 実行したソースの位置
+type Position struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// Trace 名前を付けた実行経路
+type Trace struct {
+	Name      string     `json:"name"`
+	Positions []Position `json:"positions"`
+}
+
+var (
+	mu        sync.Mutex
+	positions []Position
+)
+
+// Mark は呼び出し元の位置を実行経路に追加する
+func Mark() {
+	_, file, line, ok := runtime.Caller(1)
+	if !ok {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	positions = append(positions, Position{File: file, Line: line})
+}
+
+// Reset は記録した実行経路を破棄する
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	positions = nil
+}
+
+// Positions は記録した実行経路を返す
+func Positions() []Position {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Position(nil), positions...)
+}
+
+// Save は記録した実行経路を name という名前のトレースとして path に書き出す
+func Save(path, name string) error {
+	data, err := json.MarshalIndent(Trace{Name: name, Positions: Positions()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}